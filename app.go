@@ -0,0 +1,131 @@
+package main
+
+import "sort"
+
+// App is a deliverable application. Each app has its own repository, version
+// file, release destinations and the Slack channels it can be released from.
+type App struct {
+	Name         string
+	Service      *GitHubService
+	VersionFiles []VersionFileConfig
+	Destinations []Destination
+	ChannelIDs   []string
+
+	VersioningScheme      string
+	CalVerFormat          string
+	BuildNumberStrategy   string
+	BuildNumberTagPattern string
+	ReleaseNotes          []ReleaseNoteConfig
+}
+
+type Apps []*App
+
+func NewApps(config *Config) Apps {
+	var apps Apps
+	for _, ac := range config.Apps {
+		repo := GitHubRepository{Owner: ac.GitHubRepositoryOwner, Name: ac.GitHubRepositoryName}
+		author := CommitAuthor{Name: config.GitCommitAuthorName, Email: config.GitCommitAuthorEmail}
+		apps = append(apps, &App{
+			Name:         ac.Name,
+			Service:      NewGitHubService(config.GitHubToken, repo, author),
+			VersionFiles: ac.VersionFiles,
+			Destinations: ac.Destinations,
+			ChannelIDs:   ac.ChannelIDs,
+
+			VersioningScheme:      ac.VersioningScheme,
+			CalVerFormat:          ac.CalVerFormat,
+			BuildNumberStrategy:   ac.BuildNumberStrategy,
+			BuildNumberTagPattern: ac.BuildNumberTagPattern,
+			ReleaseNotes:          ac.ReleaseNotes,
+		})
+	}
+	return apps
+}
+
+func (apps Apps) Find(name string) *App {
+	for _, app := range apps {
+		if app.Name == name {
+			return app
+		}
+	}
+	return nil
+}
+
+// ForChannel returns the apps that can be released from the channel.
+// An app without channel restrictions can be released from any channel.
+func (apps Apps) ForChannel(channelID string) Apps {
+	var found Apps
+	for _, app := range apps {
+		if app.AllowsChannel(channelID) {
+			found = append(found, app)
+		}
+	}
+	return found
+}
+
+func (apps Apps) ChannelIDs() []string {
+	var channelIDs []string
+	for _, app := range apps {
+		channelIDs = append(channelIDs, app.ChannelIDs...)
+	}
+	return channelIDs
+}
+
+func (apps Apps) Names() []string {
+	var names []string
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+	return names
+}
+
+func (app *App) AllowsChannel(channelID string) bool {
+	if len(app.ChannelIDs) == 0 {
+		return true
+	}
+	for _, id := range app.ChannelIDs {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// Channels returns the pre-release channels accepted by any destination of
+// the app.
+func (app *App) Channels() []string {
+	var channels []string
+	for _, destination := range app.Destinations {
+		for _, channel := range destination.Channels {
+			if channel != finalChannel && !containsString(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// DestinationsFor returns the destinations that accept the version.
+func (app *App) DestinationsFor(version string) []Destination {
+	var destinations []Destination
+	for _, destination := range app.Destinations {
+		if destination.Accepts(version) {
+			destinations = append(destinations, destination)
+		}
+	}
+	return destinations
+}
+
+func (destination Destination) Accepts(version string) bool {
+	return len(destination.Channels) == 0 || containsString(destination.Channels, versionChannel(version))
+}
+
+func (app *App) Destination(name string) *Destination {
+	for i := range app.Destinations {
+		if app.Destinations[i].Name == name {
+			return &app.Destinations[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppsForChannel(t *testing.T) {
+	apps := Apps{
+		{Name: "ios", ChannelIDs: []string{"C1", "C2"}},
+		{Name: "android", ChannelIDs: []string{"C2"}},
+		{Name: "web"},
+	}
+
+	tests := []struct {
+		channelID string
+		want      []string
+	}{
+		{channelID: "C1", want: []string{"ios", "web"}},
+		{channelID: "C2", want: []string{"ios", "android", "web"}},
+		{channelID: "C3", want: []string{"web"}},
+	}
+
+	for _, test := range tests {
+		if got := apps.ForChannel(test.channelID).Names(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ForChannel(%q) = %q, want %q", test.channelID, got, test.want)
+		}
+	}
+
+	if app := apps.Find("android"); app == nil || app.Name != "android" {
+		t.Errorf("Find(%q) = %v", "android", app)
+	}
+	if app := apps.Find("Android"); app != nil {
+		t.Errorf("Find(%q) = %v, want nil", "Android", app)
+	}
+}
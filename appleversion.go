@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxMarketingVersionComponents is the number of components Apple allows in
+// CFBundleShortVersionString.
+const maxMarketingVersionComponents = 3
+
+// appleVersion is a version made of period-separated integers, the format of
+// CFBundleShortVersionString (1.2, 1.2.3) and CFBundleVersion (42, 1.2.3.4).
+type appleVersion []uint64
+
+// parseAppleVersion parses s, allowing up to max components. max <= 0 allows
+// any number of components.
+func parseAppleVersion(s string, max int) (appleVersion, bool) {
+	if s == "" {
+		return nil, false
+	}
+	components := strings.Split(s, ".")
+	if max > 0 && len(components) > max {
+		return nil, false
+	}
+
+	var version appleVersion
+	for _, component := range components {
+		if component == "" || strings.TrimLeft(component, "0123456789") != "" {
+			return nil, false
+		}
+		n, err := strconv.ParseUint(component, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		version = append(version, n)
+	}
+	return version, true
+}
+
+// bump increments the component at index and resets the following ones to
+// zero. The number of components is kept unless the version is too short to
+// have the component, e.g. 1.2 becomes 1.2.1 when the third one is bumped.
+func (version appleVersion) bump(index int) appleVersion {
+	next := make(appleVersion, len(version))
+	copy(next, version)
+	for len(next) <= index {
+		next = append(next, 0)
+	}
+	next[index]++
+	for i := index + 1; i < len(next); i++ {
+		next[i] = 0
+	}
+	return next
+}
+
+// compare compares two versions like strings.Compare. Missing components
+// are zero, so 1.2 equals 1.2.0.
+func (version appleVersion) compare(other appleVersion) int {
+	for i := 0; i < len(version) || i < len(other); i++ {
+		var a, b uint64
+		if i < len(version) {
+			a = version[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (version appleVersion) String() string {
+	components := make([]string, len(version))
+	for i, n := range version {
+		components[i] = strconv.FormatUint(n, 10)
+	}
+	return strings.Join(components, ".")
+}
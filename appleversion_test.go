@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestParseAppleVersion(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+		ok   bool
+	}{
+		{s: "1", max: 3, want: "1", ok: true},
+		{s: "1.2", max: 3, want: "1.2", ok: true},
+		{s: "1.2.3", max: 3, want: "1.2.3", ok: true},
+		{s: "01.02", max: 3, want: "1.2", ok: true},
+		{s: "1.2.3.4", max: 3},
+		{s: "1.2.3.4", max: 0, want: "1.2.3.4", ok: true},
+		{s: "", max: 3},
+		{s: "1..2", max: 3},
+		{s: "1.2.", max: 3},
+		{s: "1.2a", max: 3},
+		{s: "-1.2", max: 3},
+		{s: "+1.2", max: 3},
+		{s: "1.2.0-beta.1", max: 3},
+		{s: "99999999999999999999", max: 3},
+	}
+
+	for _, test := range tests {
+		version, ok := parseAppleVersion(test.s, test.max)
+		if ok != test.ok || ok && version.String() != test.want {
+			t.Errorf("parseAppleVersion(%q, %d) = %s, %v, want %s, %v", test.s, test.max, version, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestAppleVersionBump(t *testing.T) {
+	tests := []struct {
+		version string
+		index   int
+		want    string
+	}{
+		{version: "1.2", index: 0, want: "2.0"},
+		{version: "1.2", index: 1, want: "1.3"},
+		{version: "1.2", index: 2, want: "1.2.1"},
+		{version: "1.2.3", index: 0, want: "2.0.0"},
+		{version: "1.2.3", index: 1, want: "1.3.0"},
+		{version: "1.2.3", index: 2, want: "1.2.4"},
+		{version: "1", index: 1, want: "1.1"},
+		{version: "1.2.3.9", index: 3, want: "1.2.3.10"},
+	}
+
+	for _, test := range tests {
+		version, _ := parseAppleVersion(test.version, 0)
+		if got := version.bump(test.index).String(); got != test.want {
+			t.Errorf("%s.bump(%d) = %s, want %s", test.version, test.index, got, test.want)
+		}
+		if got := version.String(); got != test.version {
+			t.Errorf("bump changed %s to %s", test.version, got)
+		}
+	}
+}
+
+func TestAppleVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2", b: "1.2.0", want: 0},
+		{a: "1.2", b: "1.2.1", want: -1},
+		{a: "1.10", b: "1.9", want: 1},
+		{a: "2", b: "1.9.9", want: 1},
+		{a: "1.2.3.4", b: "1.2.3.5", want: -1},
+	}
+
+	for _, test := range tests {
+		a, _ := parseAppleVersion(test.a, 0)
+		b, _ := parseAppleVersion(test.b, 0)
+		if got := a.compare(b); got != test.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := b.compare(a); got != -test.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Block Kit layout blocks and elements used in messages.
+// https://api.slack.com/reference/block-kit
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type optionObject struct {
+	Text  *textObject `json:"text"`
+	Value string      `json:"value"`
+}
+
+type confirmObject struct {
+	Title   *textObject `json:"title"`
+	Text    *textObject `json:"text"`
+	Confirm *textObject `json:"confirm"`
+	Deny    *textObject `json:"deny"`
+}
+
+// blockElement is an interactive element: a button, a static_select, a
+// plain_text_input or checkboxes.
+type blockElement struct {
+	Type          string         `json:"type"`
+	ActionID      string         `json:"action_id"`
+	Text          *textObject    `json:"text,omitempty"`
+	Value         string         `json:"value,omitempty"`
+	Style         string         `json:"style,omitempty"`
+	Placeholder   *textObject    `json:"placeholder,omitempty"`
+	Options       []optionObject `json:"options,omitempty"`
+	InitialOption *optionObject  `json:"initial_option,omitempty"`
+	InitialValue  string         `json:"initial_value,omitempty"`
+	Multiline     bool           `json:"multiline,omitempty"`
+	Confirm       *confirmObject `json:"confirm,omitempty"`
+}
+
+type block struct {
+	Type    string      `json:"type"`
+	BlockID string      `json:"block_id,omitempty"`
+	Text    *textObject `json:"text,omitempty"`
+	// Elements are blockElements in an actions block and textObjects in a
+	// context block.
+	Elements []interface{} `json:"elements,omitempty"`
+
+	// Input blocks of modals.
+	Label    *textObject   `json:"label,omitempty"`
+	Element  *blockElement `json:"element,omitempty"`
+	Hint     *textObject   `json:"hint,omitempty"`
+	Optional bool          `json:"optional,omitempty"`
+}
+
+// view is a modal.
+// https://api.slack.com/reference/surfaces/views
+type view struct {
+	Type            string      `json:"type"`
+	CallbackID      string      `json:"callback_id,omitempty"`
+	Title           *textObject `json:"title"`
+	Submit          *textObject `json:"submit,omitempty"`
+	Close           *textObject `json:"close,omitempty"`
+	PrivateMetadata string      `json:"private_metadata,omitempty"`
+	Blocks          []block     `json:"blocks"`
+}
+
+func markdownText(text string) *textObject {
+	return &textObject{Type: "mrkdwn", Text: text}
+}
+
+func plainText(text string) *textObject {
+	return &textObject{Type: "plain_text", Text: text}
+}
+
+func sectionBlock(text string) block {
+	return block{Type: "section", Text: markdownText(text)}
+}
+
+func contextBlock(text string) block {
+	return block{Type: "context", Elements: []interface{}{markdownText(text)}}
+}
+
+// actionsBlock lays out the elements. Action IDs must be unique in a block,
+// so repeated ones are numbered as "version:1", "version:2", and so on.
+func actionsBlock(blockID string, elements []blockElement) block {
+	b := block{Type: "actions", BlockID: blockID}
+	seen := map[string]int{}
+	for _, element := range elements {
+		if n := seen[element.ActionID]; n > 0 {
+			seen[element.ActionID]++
+			element.ActionID = fmt.Sprintf("%s:%d", element.ActionID, n)
+		} else {
+			seen[element.ActionID] = 1
+		}
+		b.Elements = append(b.Elements, element)
+	}
+	return b
+}
+
+// actionName returns the action ID without the number actionsBlock adds.
+func actionName(actionID string) string {
+	return strings.SplitN(actionID, ":", 2)[0]
+}
+
+// inputBlock is an input of a modal. The block ID is also the action ID of
+// the element, so that submitted values are looked up by one name.
+func inputBlock(id, label string, element blockElement) block {
+	element.ActionID = id
+	return block{Type: "input", BlockID: id, Label: plainText(label), Element: &element}
+}
+
+func buttonElement(actionID, text, value, style string) blockElement {
+	return blockElement{
+		Type:     "button",
+		ActionID: actionID,
+		Text:     plainText(text),
+		Value:    value,
+		Style:    style,
+	}
+}
+
+// maxSelectOptions is the number of options Slack allows in a select menu.
+const maxSelectOptions = 100
+
+// selectElement is a static_select. The values of its options are limited to
+// 150 characters.
+func selectElement(actionID, placeholder string, options []optionObject) blockElement {
+	if len(options) > maxSelectOptions {
+		options = options[:maxSelectOptions]
+	}
+	return blockElement{
+		Type:        "static_select",
+		ActionID:    actionID,
+		Placeholder: plainText(placeholder),
+		Options:     options,
+	}
+}
+
+func textInputElement(initialValue string, multiline bool) blockElement {
+	return blockElement{
+		Type:         "plain_text_input",
+		InitialValue: initialValue,
+		Multiline:    multiline,
+	}
+}
+
+func checkboxesElement(options []optionObject) blockElement {
+	return blockElement{Type: "checkboxes", Options: options}
+}
+
+func option(text, value string) optionObject {
+	return optionObject{Text: plainText(text), Value: value}
+}
+
+func confirmDialog(title, text, confirm string) *confirmObject {
+	return &confirmObject{
+		Title:   plainText(title),
+		Text:    markdownText(text),
+		Confirm: plainText(confirm),
+		Deny:    plainText("Cancel"),
+	}
+}
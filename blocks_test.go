@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestActionsBlock(t *testing.T) {
+	elements := []blockElement{
+		buttonElement("version", "1.2.4", "1.2.4", ""),
+		buttonElement("version", "1.3.0", "1.3.0", ""),
+		buttonElement("cancel", "Cancel", "", "danger"),
+		buttonElement("version", "2.0.0", "2.0.0", ""),
+	}
+
+	b := actionsBlock("versions", elements)
+
+	var actionIDs []string
+	for _, element := range b.Elements {
+		actionIDs = append(actionIDs, element.(blockElement).ActionID)
+	}
+	if want := []string{"version", "version:1", "cancel", "version:2"}; !reflect.DeepEqual(actionIDs, want) {
+		t.Errorf("action IDs = %q, want %q", actionIDs, want)
+	}
+	if b.Type != "actions" || b.BlockID != "versions" {
+		t.Errorf("block = %q %q, want actions versions", b.Type, b.BlockID)
+	}
+	// The elements passed in are not changed.
+	if elements[1].ActionID != "version" {
+		t.Errorf("elements[1].ActionID = %q, want %q", elements[1].ActionID, "version")
+	}
+
+	for i, want := range []string{"version", "version", "cancel", "version"} {
+		if got := actionName(actionIDs[i]); got != want {
+			t.Errorf("actionName(%q) = %q, want %q", actionIDs[i], got, want)
+		}
+	}
+}
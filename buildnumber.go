@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBuildNumberStrategy = "increment"
+	// defaultBuildNumberTagPattern matches the build number in tags such as
+	// v1.2.3+45.
+	defaultBuildNumberTagPattern = `\+(?P<build>[0-9]+)$`
+	buildNumberTagGroup          = "build"
+)
+
+// buildNumberRequest is what a build number strategy picks the build number
+// of the next release from.
+type buildNumberRequest struct {
+	app                *App
+	branch             string
+	currentVersion     string
+	currentBuildNumber string
+	// nextBuildNumber is the build number after the current one as the
+	// version file counts it.
+	nextBuildNumber string
+	version         string
+	now             time.Time
+}
+
+type buildNumberStrategy struct {
+	description string
+	next        func(request buildNumberRequest) (string, error)
+}
+
+var buildNumberStrategies = map[string]buildNumberStrategy{
+	"increment": {
+		description: "current build number + 1",
+		next: func(request buildNumberRequest) (string, error) {
+			return request.nextBuildNumber, nil
+		},
+	},
+	"date": {
+		description: "date (YYYYMMDDNN)",
+		next:        nextDateBuildNumber,
+	},
+	"commit_count": {
+		description: "commit count of the branch",
+		next: func(request buildNumberRequest) (string, error) {
+			count, err := request.app.Service.CommitCount(request.branch)
+			if err != nil {
+				return "", err
+			}
+			// The release commit is pushed on top of the branch.
+			return strconv.Itoa(count + 1), nil
+		},
+	},
+	"tags": {
+		description: "highest build number in tags + 1",
+		next:        nextTagBuildNumber,
+	},
+	"reset": {
+		description: "1 for a new version, otherwise current build number + 1",
+		next: func(request buildNumberRequest) (string, error) {
+			if request.version != request.currentVersion {
+				return "1", nil
+			}
+			return request.nextBuildNumber, nil
+		},
+	},
+}
+
+func buildNumberStrategyNames() []string {
+	var names []string
+	for name := range buildNumberStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nextDateBuildNumber returns today's date followed by a two-digit counter of
+// the builds of the day, e.g. 2018061302 for the second build on 2018-06-13.
+func nextDateBuildNumber(request buildNumberRequest) (string, error) {
+	date := request.now.Format("20060102")
+	count := 1
+	if current := request.currentBuildNumber; len(current) == len(date)+2 && strings.HasPrefix(current, date) {
+		n, err := strconv.Atoi(current[len(date):])
+		if err == nil {
+			count = n + 1
+		}
+	}
+	if count > 99 {
+		return "", fmt.Errorf("no more build numbers left for %s", request.now.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s%02d", date, count), nil
+}
+
+// nextTagBuildNumber returns the highest build number found in the tags of
+// the repository plus one.
+func nextTagBuildNumber(request buildNumberRequest) (string, error) {
+	pattern, err := regexp.Compile(request.app.BuildNumberTagPattern)
+	if err != nil {
+		return "", err
+	}
+	tags, err := request.app.Service.Tags()
+	if err != nil {
+		return "", err
+	}
+
+	var highest uint64
+	found := false
+	for _, tag := range tags {
+		m := pattern.FindStringSubmatch(tag.GetName())
+		if m == nil {
+			continue
+		}
+		n, err := strconv.ParseUint(m[pattern.SubexpIndex(buildNumberTagGroup)], 10, 64)
+		if err != nil {
+			continue
+		}
+		if !found || n > highest {
+			highest = n
+			found = true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("no tags match %s", pattern)
+	}
+	return strconv.FormatUint(highest+1, 10), nil
+}
+
+func validateBuildNumberStrategy(strategy, tagPattern string) error {
+	if _, ok := buildNumberStrategies[strategy]; !ok {
+		return fmt.Errorf("unknown build number strategy %q (available: %v)", strategy, buildNumberStrategyNames())
+	}
+	if tagPattern == "" {
+		return nil
+	}
+	if strategy != "tags" {
+		return fmt.Errorf("build_number_tag_pattern is only supported by the tags build number strategy")
+	}
+	pattern, err := regexp.Compile(tagPattern)
+	if err != nil {
+		return fmt.Errorf("invalid build_number_tag_pattern: %s", err)
+	}
+	if pattern.SubexpIndex(buildNumberTagGroup) < 0 {
+		return fmt.Errorf("build_number_tag_pattern has no (?P<%s>...) group", buildNumberTagGroup)
+	}
+	return nil
+}
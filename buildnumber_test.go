@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNextDateBuildNumber(t *testing.T) {
+	now := time.Date(2018, 6, 13, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		current string
+		want    string
+		wantErr bool
+	}{
+		{current: "42", want: "2018061301"},
+		{current: "2018061201", want: "2018061301"},
+		// The second build of the day.
+		{current: "2018061301", want: "2018061302"},
+		{current: "2018061309", want: "2018061310"},
+		{current: "2018061399", wantErr: true},
+		// A build number of another format starts the day over.
+		{current: "201806131", want: "2018061301"},
+		{current: "20180613001", want: "2018061301"},
+	}
+
+	for _, test := range tests {
+		got, err := nextDateBuildNumber(buildNumberRequest{currentBuildNumber: test.current, now: now})
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("nextDateBuildNumber(%q) = %q, want error", test.current, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("nextDateBuildNumber(%q) = %q, %v, want %q", test.current, got, err, test.want)
+		}
+	}
+}
+
+func TestBuildNumberStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		version  string
+		want     string
+	}{
+		{strategy: "increment", version: "1.2.0", want: "43"},
+		{strategy: "increment", version: "1.3.0", want: "43"},
+		{strategy: "reset", version: "1.2.0", want: "43"},
+		{strategy: "reset", version: "1.3.0", want: "1"},
+	}
+
+	for _, test := range tests {
+		request := buildNumberRequest{
+			currentVersion:     "1.2.0",
+			currentBuildNumber: "42",
+			nextBuildNumber:    "43",
+			version:            test.version,
+		}
+		got, err := buildNumberStrategies[test.strategy].next(request)
+		if err != nil || got != test.want {
+			t.Errorf("%s for %s = %q, %v, want %q", test.strategy, test.version, got, err, test.want)
+		}
+	}
+}
+
+func TestNextTagBuildNumber(t *testing.T) {
+	service := testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repository/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[{"name": "v1.2.0+9"}, {"name": "v1.1.0+10"}, {"name": "android/1.0.0(50)"}, {"name": "latest"}]`)
+	}))
+
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		// Build numbers are compared as numbers.
+		{pattern: defaultBuildNumberTagPattern, want: "11"},
+		{pattern: `^android/.*\((?P<build>[0-9]+)\)$`, want: "51"},
+		{pattern: `^ios/(?P<build>[0-9]+)$`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		app := &App{Service: service, BuildNumberTagPattern: test.pattern}
+		got, err := nextTagBuildNumber(buildNumberRequest{app: app})
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("nextTagBuildNumber(%s) = %q, want error", test.pattern, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("nextTagBuildNumber(%s) = %q, %v, want %q", test.pattern, got, err, test.want)
+		}
+	}
+}
+
+func TestValidateBuildNumberStrategy(t *testing.T) {
+	valid := []struct{ strategy, pattern string }{
+		{strategy: "increment"},
+		{strategy: "date"},
+		{strategy: "commit_count"},
+		{strategy: "reset"},
+		{strategy: "tags", pattern: defaultBuildNumberTagPattern},
+	}
+	for _, test := range valid {
+		if err := validateBuildNumberStrategy(test.strategy, test.pattern); err != nil {
+			t.Errorf("validateBuildNumberStrategy(%q, %q): %s", test.strategy, test.pattern, err)
+		}
+	}
+
+	invalid := []struct{ strategy, pattern string }{
+		{strategy: "random"},
+		{strategy: ""},
+		{strategy: "increment", pattern: defaultBuildNumberTagPattern},
+		{strategy: "tags", pattern: `(?P<build>[0-9]+`},
+		{strategy: "tags", pattern: `\+([0-9]+)$`},
+	}
+	for _, test := range invalid {
+		if err := validateBuildNumberStrategy(test.strategy, test.pattern); err == nil {
+			t.Errorf("validateBuildNumberStrategy(%q, %q) succeeded", test.strategy, test.pattern)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// otherChangesSection is the section of the changelog for pull requests
+// without labels and commits pushed without a pull request.
+const otherChangesSection = "Other changes"
+
+// pullRequestNumberPatterns find the pull request a commit merges, in merge
+// commits ("Merge pull request #123 from ...") and squashed commits
+// ("Add arrays (#123)").
+var pullRequestNumberPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge pull request #([0-9]+) `),
+	regexp.MustCompile(`\(#([0-9]+)\)$`),
+}
+
+// Changelog lists the pull requests merged since the previous release,
+// grouped by their first label.
+type Changelog struct {
+	Version     string
+	BuildNumber string
+	Date        time.Time
+	PreviousTag string
+	CompareURL  string
+	// Notes are written by hand when the release is requested.
+	Notes    string
+	Sections []ChangelogSection
+}
+
+type ChangelogSection struct {
+	Title   string
+	Entries []ChangelogEntry
+}
+
+// ChangelogEntry is a merged pull request, or a commit pushed without one.
+type ChangelogEntry struct {
+	Title string
+	// Ref is "#123" for a pull request and the short SHA for a commit.
+	Ref    string
+	URL    string
+	Author string
+}
+
+var changelogTemplate = template.Must(template.New("changelog").Parse(`## {{if .CompareURL}}[{{.Version}}]({{.CompareURL}}){{else}}{{.Version}}{{end}} ({{.Date.Format "2006-01-02"}})
+{{if .Notes}}
+{{.Notes}}
+{{end}}{{range .Sections}}
+### {{.Title}}
+{{range .Entries}}* {{.Title}} [{{.Ref}}]({{.URL}}){{if .Author}} (@{{.Author}}){{end}}
+{{end}}{{end}}`))
+
+func commitPullRequestNumber(message string) int {
+	header := strings.SplitN(message, "\n", 2)[0]
+	for _, pattern := range pullRequestNumberPatterns {
+		if m := pattern.FindStringSubmatch(header); m != nil {
+			number, _ := strconv.Atoi(m[1])
+			return number
+		}
+	}
+	return 0
+}
+
+// buildChangelog collects the pull requests merged into the branch since the
+// latest release tag reachable from it. Commits of those pull requests are
+// not listed on their own.
+func buildChangelog(service *GitHubService, version string, branch string, date time.Time) (*Changelog, error) {
+	tag, err := service.LatestTag(branch)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := service.Commits(tag.GetName(), branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	seen := map[int]bool{}
+	for _, commit := range commits {
+		if number := commitPullRequestNumber(commit.GetCommit().GetMessage()); number > 0 && !seen[number] {
+			numbers = append(numbers, number)
+			seen[number] = true
+		}
+	}
+
+	sections := map[string][]ChangelogEntry{}
+	merged := map[string]bool{}
+	for _, number := range numbers {
+		issue, err := service.Issue(number)
+		if err != nil {
+			return nil, err
+		}
+		prCommits, err := service.PullRequestCommits(number)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range prCommits {
+			merged[commit.GetSHA()] = true
+		}
+
+		section := otherChangesSection
+		if len(issue.Labels) > 0 {
+			section = issue.Labels[0].GetName()
+		}
+		sections[section] = append(sections[section], ChangelogEntry{
+			Title:  issue.GetTitle(),
+			Ref:    fmt.Sprintf("#%d", number),
+			URL:    issue.GetHTMLURL(),
+			Author: issue.GetUser().GetLogin(),
+		})
+	}
+	for _, commit := range commits {
+		if merged[commit.GetSHA()] || len(commit.Parents) > 1 || commitPullRequestNumber(commit.GetCommit().GetMessage()) > 0 {
+			continue
+		}
+		sha := commit.GetSHA()
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		sections[otherChangesSection] = append(sections[otherChangesSection], ChangelogEntry{
+			Title:  strings.SplitN(commit.GetCommit().GetMessage(), "\n", 2)[0],
+			Ref:    sha,
+			URL:    commit.GetHTMLURL(),
+			Author: commit.GetAuthor().GetLogin(),
+		})
+	}
+
+	changelog := Changelog{
+		Version:     version,
+		Date:        date,
+		PreviousTag: tag.GetName(),
+		CompareURL:  fmt.Sprintf("https://github.com/%s/%s/compare/%s...%s", service.Repository.Owner, service.Repository.Name, url.PathEscape(tag.GetName()), url.PathEscape(branch)),
+	}
+	var titles []string
+	for title := range sections {
+		if title != otherChangesSection {
+			titles = append(titles, title)
+		}
+	}
+	sort.Strings(titles)
+	if _, ok := sections[otherChangesSection]; ok {
+		titles = append(titles, otherChangesSection)
+	}
+	for _, title := range titles {
+		entries := sections[title]
+		// Newest first.
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+		changelog.Sections = append(changelog.Sections, ChangelogSection{Title: title, Entries: entries})
+	}
+	return &changelog, nil
+}
+
+// Markdown renders the changelog for the release pull request.
+func (changelog *Changelog) Markdown() (string, error) {
+	var buf bytes.Buffer
+	if err := changelogTemplate.Execute(&buf, changelog); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCommitPullRequestNumber(t *testing.T) {
+	tests := []struct {
+		message string
+		want    int
+	}{
+		{message: "Merge pull request #123 from owner/arrays\n\nAdd arrays", want: 123},
+		{message: "Add arrays (#45)", want: 45},
+		{message: "Add arrays (#45)\n\n* Add arrays\n* Fix tests (#44)", want: 45},
+		{message: "Add arrays"},
+		{message: "Fix #12"},
+		{message: "Add arrays (#45) and more"},
+		{message: "Merge branch 'main' into arrays"},
+		{message: "Update README\n\nMerge pull request #12 from owner/readme"},
+	}
+
+	for _, test := range tests {
+		if got := commitPullRequestNumber(test.message); got != test.want {
+			t.Errorf("commitPullRequestNumber(%q) = %d, want %d", test.message, got, test.want)
+		}
+	}
+}
+
+// testChangelogService returns a GitHubService with the tag v1.2.0 followed by
+// the commits on main, the issues and the commits of the pull requests.
+func testChangelogService(t *testing.T, commits []interface{}, issues map[string]interface{}, pullRequestCommits map[string][]interface{}) *GitHubService {
+	return testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch r.URL.Path {
+		case "/repos/owner/repository/tags":
+			body = []interface{}{map[string]interface{}{"name": "v1.2.0", "commit": map[string]string{"sha": "t1"}}}
+		case "/repos/owner/repository/compare/t1...main", "/repos/owner/repository/compare/v1.2.0...main":
+			body = map[string]interface{}{"status": "ahead", "commits": commits}
+		default:
+			for number, issue := range issues {
+				if r.URL.Path == "/repos/owner/repository/issues/"+number {
+					body = issue
+				}
+				if r.URL.Path == "/repos/owner/repository/pulls/"+number+"/commits" {
+					body = pullRequestCommits[number]
+				}
+			}
+		}
+		if body == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+}
+
+func testCommit(sha, message string, parents int) map[string]interface{} {
+	commit := map[string]interface{}{
+		"sha":      sha,
+		"html_url": "https://github.com/owner/repository/commit/" + sha,
+		"commit":   map[string]string{"message": message},
+		"author":   map[string]string{"login": "alice"},
+	}
+	var list []interface{}
+	for i := 0; i < parents; i++ {
+		list = append(list, map[string]string{"sha": "p"})
+	}
+	commit["parents"] = list
+	return commit
+}
+
+func testIssue(number, title string, labels ...string) map[string]interface{} {
+	var list []interface{}
+	for _, label := range labels {
+		list = append(list, map[string]string{"name": label})
+	}
+	return map[string]interface{}{
+		"title":    title,
+		"html_url": "https://github.com/owner/repository/pull/" + number,
+		"user":     map[string]string{"login": "bob"},
+		"labels":   list,
+	}
+}
+
+func TestBuildChangelog(t *testing.T) {
+	commits := []interface{}{
+		testCommit("c1000000000", "Add arrays (#12)", 1),
+		testCommit("c2000000000", "Handle nil", 1),
+		testCommit("c3000000000", "Merge pull request #13 from owner/nil\n\nHandle nil", 2),
+		testCommit("c4000000000", "Update README", 1),
+		testCommit("c5000000000", "Merge branch 'main' into release", 2),
+		testCommit("c6000000000", "Tweak icons (#14)", 1),
+		testCommit("c7000000000", "Revert \"Add arrays (#12)\"\n\nMerge pull request #12 again", 1),
+		testCommit("c8000000000", "Add maps (#15)", 1),
+	}
+	issues := map[string]interface{}{
+		"12": testIssue("12", "Add arrays", "enhancement", "bug"),
+		"13": testIssue("13", "Handle nil", "bug"),
+		"14": testIssue("14", "Tweak icons"),
+		"15": testIssue("15", "Add maps", "enhancement"),
+	}
+	pullRequestCommits := map[string][]interface{}{
+		"13": {testCommit("c2000000000", "Handle nil", 1)},
+	}
+	service := testChangelogService(t, commits, issues, pullRequestCommits)
+	date := time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC)
+
+	changelog, err := buildChangelog(service, "1.3.0", "main", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changelog.PreviousTag != "v1.2.0" {
+		t.Errorf("PreviousTag = %q, want %q", changelog.PreviousTag, "v1.2.0")
+	}
+	if want := "https://github.com/owner/repository/compare/v1.2.0...main"; changelog.CompareURL != want {
+		t.Errorf("CompareURL = %q, want %q", changelog.CompareURL, want)
+	}
+	// Pull requests are grouped by their first label, newest first; labels
+	// are sorted and pull requests without labels and commits pushed without
+	// a pull request come last. Commits of pull requests are not listed.
+	want := []ChangelogSection{
+		{Title: "bug", Entries: []ChangelogEntry{
+			{Title: "Handle nil", Ref: "#13", URL: "https://github.com/owner/repository/pull/13", Author: "bob"},
+		}},
+		{Title: "enhancement", Entries: []ChangelogEntry{
+			{Title: "Add maps", Ref: "#15", URL: "https://github.com/owner/repository/pull/15", Author: "bob"},
+			{Title: "Add arrays", Ref: "#12", URL: "https://github.com/owner/repository/pull/12", Author: "bob"},
+		}},
+		{Title: otherChangesSection, Entries: []ChangelogEntry{
+			{Title: "Revert \"Add arrays (#12)\"", Ref: "c700000", URL: "https://github.com/owner/repository/commit/c7000000000", Author: "alice"},
+			{Title: "Update README", Ref: "c400000", URL: "https://github.com/owner/repository/commit/c4000000000", Author: "alice"},
+			{Title: "Tweak icons", Ref: "#14", URL: "https://github.com/owner/repository/pull/14", Author: "bob"},
+		}},
+	}
+	if !reflect.DeepEqual(changelog.Sections, want) {
+		t.Errorf("Sections = %+v, want %+v", changelog.Sections, want)
+	}
+}
+
+func TestChangelogMarkdown(t *testing.T) {
+	date := time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC)
+	sections := []ChangelogSection{{Title: "bug", Entries: []ChangelogEntry{
+		{Title: "Handle nil", Ref: "#13", URL: "https://github.com/owner/repository/pull/13", Author: "bob"},
+		{Title: "Update README", Ref: "c400000", URL: "https://github.com/owner/repository/commit/c4000000000"},
+	}}}
+
+	tests := []struct {
+		name      string
+		changelog Changelog
+		want      string
+	}{
+		{
+			name:      "compare URL",
+			changelog: Changelog{Version: "1.3.0", Date: date, CompareURL: "https://github.com/owner/repository/compare/v1.2.0...main", Notes: "Arrays!", Sections: sections},
+			want: "## [1.3.0](https://github.com/owner/repository/compare/v1.2.0...main) (2018-06-13)\n\nArrays!\n\n" +
+				"### bug\n* Handle nil [#13](https://github.com/owner/repository/pull/13) (@bob)\n* Update README [c400000](https://github.com/owner/repository/commit/c4000000000)\n",
+		},
+		{
+			// Without a previous release there is nothing to compare with.
+			name:      "no compare URL",
+			changelog: Changelog{Version: "1.3.0", Date: date},
+			want:      "## 1.3.0 (2018-06-13)\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.changelog.Markdown()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Markdown() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommitsEscapesRefs(t *testing.T) {
+	var requestURI string
+	service := testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		w.Write([]byte(`{"status": "ahead", "commits": []}`))
+	}))
+
+	if _, err := service.Commits("v1.2.0+45", "feature/a#1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/repos/owner/repository/compare/v1.2.0+45...feature%2Fa%231?per_page=100&page=1"; requestURI != want {
+		t.Errorf("request URI = %q, want %q", requestURI, want)
+	}
+}
@@ -0,0 +1,207 @@
+// Package command routes the commands sent to deliverbot by mentioning it or
+// in a direct message. Commands are registered before the bot starts, usually
+// from init functions of packages imported by the main package.
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Types of command arguments.
+const (
+	ArgString = "string"
+	ArgInt    = "int"
+	// ArgApp is the name of an app that can be delivered from the channel.
+	ArgApp = "app"
+)
+
+// Arg is an argument of a command. Optional arguments must come last.
+type Arg struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+// Command is run when the bot is mentioned as `@deliverbot <name> [args]`,
+// or sent `<name> [args]` in a direct message.
+type Command struct {
+	Name        string
+	Description string
+	Args        []Arg
+	Run         func(request *Request) error
+}
+
+// Bot is the bot a command is sent to.
+type Bot interface {
+	// ID returns the user ID of the bot.
+	ID() string
+	// Reply posts a message to the channel.
+	Reply(channel, text string) error
+	// Apps returns the names of the apps that can be delivered from the
+	// channel.
+	Apps(channel string) []string
+	// Deliver starts the release wizard of the app, or of any app that can
+	// be delivered from the channel if app is empty.
+	Deliver(channel, app string) error
+}
+
+// Request is a command sent to the bot.
+type Request struct {
+	Bot     Bot
+	Channel string
+	User    string
+	// Args are the values of the given arguments: a string or an int
+	// depending on the type of the argument. Apps are given by name.
+	Args map[string]interface{}
+}
+
+var (
+	mu       sync.RWMutex
+	commands = map[string]Command{}
+)
+
+// Register adds a command to the bot. Registering a name twice panics.
+func Register(command Command) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := commands[command.Name]; ok {
+		panic(fmt.Sprintf("command %q is already registered", command.Name))
+	}
+	commands[command.Name] = command
+}
+
+// Commands returns the registered commands sorted by name.
+func Commands() []Command {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var list []Command
+	for _, command := range commands {
+		list = append(list, command)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (request *Request) String(name string) string {
+	value, _ := request.Args[name].(string)
+	return value
+}
+
+func (request *Request) Int(name string) int {
+	value, _ := request.Args[name].(int)
+	return value
+}
+
+// App returns the name of the app given in the argument.
+func (request *Request) App(name string) string {
+	return request.String(name)
+}
+
+// Reply posts a message to the channel the command was sent to.
+func (request *Request) Reply(text string) error {
+	return request.Bot.Reply(request.Channel, text)
+}
+
+// Usage returns the usage of the command, such as `deliver [app]`.
+func (command Command) Usage() string {
+	usage := []string{command.Name}
+	for _, arg := range command.Args {
+		if arg.Optional {
+			usage = append(usage, fmt.Sprintf("[%s]", arg.Name))
+		} else {
+			usage = append(usage, fmt.Sprintf("<%s>", arg.Name))
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
+// parseArgs converts the arguments to the types of the command.
+func (command Command) parseArgs(request *Request, values []string) error {
+	if len(values) > len(command.Args) {
+		return fmt.Errorf("too many arguments")
+	}
+	request.Args = map[string]interface{}{}
+	for i, arg := range command.Args {
+		if i >= len(values) {
+			if !arg.Optional {
+				return fmt.Errorf("%s is missing", arg.Name)
+			}
+			continue
+		}
+
+		switch arg.Type {
+		case ArgInt:
+			n, err := strconv.Atoi(values[i])
+			if err != nil {
+				return fmt.Errorf("%s must be a number: %s", arg.Name, values[i])
+			}
+			request.Args[arg.Name] = n
+		case ArgApp:
+			apps := request.Bot.Apps(request.Channel)
+			if !hasApp(apps, values[i]) {
+				return fmt.Errorf("unknown app `%s`. Available apps: `%s`", values[i], strings.Join(apps, "`, `"))
+			}
+			request.Args[arg.Name] = values[i]
+		default:
+			request.Args[arg.Name] = values[i]
+		}
+	}
+	return nil
+}
+
+func hasApp(apps []string, name string) bool {
+	for _, app := range apps {
+		if app == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the command with the arguments. Mistakes in the command are
+// replied to the channel.
+func Run(bot Bot, channel, user, name string, values []string) error {
+	mu.RLock()
+	command, ok := commands[name]
+	mu.RUnlock()
+	if !ok {
+		return bot.Reply(channel, fmt.Sprintf("Unknown command `%s`.\n%s", name, Help(bot)))
+	}
+
+	request := &Request{
+		Bot:     bot,
+		Channel: channel,
+		User:    user,
+	}
+	if err := command.parseArgs(request, values); err != nil {
+		return bot.Reply(channel, fmt.Sprintf("%s\nUsage: <@%s> `%s`", capitalize(err.Error()), bot.ID(), command.Usage()))
+	}
+	return command.Run(request)
+}
+
+// capitalize upper-cases the first letter of an error shown in a reply.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// Help lists the registered commands.
+func Help(bot Bot) string {
+	lines := []string{"*Usage:*"}
+	for _, command := range Commands() {
+		lines = append(lines, fmt.Sprintf("<@%s> `%s`  %s", bot.ID(), command.Usage(), command.Description))
+	}
+	lines = append(lines, "In a direct message, send the commands without the mention.")
+	return strings.Join(lines, "\n")
+}
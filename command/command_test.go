@@ -0,0 +1,89 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+type testBot struct {
+	replies   []string
+	delivered string
+}
+
+func (bot *testBot) ID() string { return "U1" }
+
+func (bot *testBot) Reply(channel, text string) error {
+	bot.replies = append(bot.replies, text)
+	return nil
+}
+
+func (bot *testBot) Apps(channel string) []string { return []string{"ios", "android"} }
+
+func (bot *testBot) Deliver(channel, app string) error {
+	bot.delivered = app
+	return nil
+}
+
+func init() {
+	Register(Command{
+		Name: "test-deliver",
+		Args: []Arg{{Name: "app", Type: ArgApp}, {Name: "count", Type: ArgInt, Optional: true}},
+		Run: func(request *Request) error {
+			if request.Int("count") > 0 {
+				return request.Reply(strings.Repeat(request.App("app"), request.Int("count")))
+			}
+			return request.Bot.Deliver(request.Channel, request.App("app"))
+		},
+	})
+}
+
+func TestUsage(t *testing.T) {
+	command := Command{Name: "deliver", Args: []Arg{{Name: "app"}, {Name: "branch", Optional: true}}}
+	if got, want := command.Usage(), "deliver <app> [branch]"; got != want {
+		t.Errorf("Usage() = %q, want %q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []string
+		reply     string
+		delivered string
+	}{
+		{name: "test-deliver", values: []string{"ios"}, delivered: "ios"},
+		{name: "test-deliver", values: []string{"ios", "2"}, reply: "iosios"},
+		{name: "test-deliver", values: []string{"web"}, reply: "Unknown app `web`. Available apps: `ios`, `android`"},
+		{name: "test-deliver", values: []string{"ios", "two"}, reply: "Count must be a number: two"},
+		{name: "test-deliver", reply: "App is missing\nUsage: <@U1> `test-deliver <app> [count]`"},
+		{name: "test-deliver", values: []string{"ios", "2", "3"}, reply: "Too many arguments"},
+		{name: "unknown", reply: "Unknown command `unknown`."},
+	}
+
+	for _, test := range tests {
+		bot := &testBot{}
+		if err := Run(bot, "C1", "U2", test.name, test.values); err != nil {
+			t.Errorf("Run(%q, %q): %s", test.name, test.values, err)
+			continue
+		}
+		if bot.delivered != test.delivered {
+			t.Errorf("Run(%q, %q) delivered %q, want %q", test.name, test.values, bot.delivered, test.delivered)
+		}
+		if test.reply == "" {
+			if len(bot.replies) != 0 {
+				t.Errorf("Run(%q, %q) replied %q", test.name, test.values, bot.replies)
+			}
+		} else if len(bot.replies) != 1 || !strings.HasPrefix(bot.replies[0], test.reply) {
+			t.Errorf("Run(%q, %q) replied %q, want %q", test.name, test.values, bot.replies, test.reply)
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register did not panic")
+		}
+	}()
+	Register(Command{Name: "test-deliver"})
+}
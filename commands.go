@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/kishikawakatsumi/deliverbot/command"
+)
+
+func init() {
+	command.Register(command.Command{
+		Name:        "deliver",
+		Description: "Start the release wizard. Mentioning the bot alone does the same.",
+		Args:        []command.Arg{{Name: "app", Type: command.ArgApp, Optional: true}},
+		Run: func(request *command.Request) error {
+			return request.Bot.Deliver(request.Channel, request.App("app"))
+		},
+	})
+	command.Register(command.Command{
+		Name:        "help",
+		Description: "Show this help.",
+		Run: func(request *command.Request) error {
+			return request.Reply(command.Help(request.Bot))
+		},
+	})
+	command.Register(command.Command{
+		Name:        "ping",
+		Description: "Check that the bot is running.",
+		Run: func(request *command.Request) error {
+			return request.Reply("pong")
+		},
+	})
+}
+
+// ID returns the user ID of the bot. With Reply, Apps and Deliver, it makes
+// SlackListener the command.Bot the commands are sent to.
+func (s *SlackListener) ID() string {
+	return s.botID
+}
+
+func (s *SlackListener) Reply(channel, text string) error {
+	return s.respond(channel, text)
+}
+
+func (s *SlackListener) Apps(channel string) []string {
+	return s.channelApps(channel).Names()
+}
+
+func (s *SlackListener) Deliver(channel, app string) error {
+	return s.deliver(channel, app)
+}
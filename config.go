@@ -1,13 +1,23 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/kelseyhightower/envconfig"
 	toml "github.com/sioncojp/tomlssm"
 )
 
+const (
+	slackModeEvents = "events"
+	slackModeRTM    = "rtm"
+)
+
 type Config struct {
 	BotToken              string
 	VerificationToken     string
+	SigningSecret         string
+	SlackMode             string
 	BotID                 string
 	ChannelID             string
 	DebugChannelID        string
@@ -18,11 +28,47 @@ type Config struct {
 	GitCommitAuthorName   string
 	GitCommitAuthorEmail  string
 	InfoPlistPath         string
+	SessionStore          string
+	SessionPath           string
+	Apps                  []AppConfig
+}
+
+type AppConfig struct {
+	Name                  string
+	GitHubRepositoryOwner string
+	GitHubRepositoryName  string
+	VersionFiles          []VersionFileConfig
+	VersioningScheme      string
+	CalVerFormat          string
+	BuildNumberStrategy   string
+	BuildNumberTagPattern string
+	ReleaseNotes          []ReleaseNoteConfig
+	Destinations          []Destination
+	ChannelIDs            []string
+}
+
+type Destination struct {
+	Name         string `toml:"name"`
+	Label        string `toml:"label"`
+	Description  string `toml:"description"`
+	BranchPrefix string `toml:"branch_prefix"`
+	// Channels limits the versions the destination accepts by pre-release
+	// channel, such as "beta" for 2.0.0-beta.3 or "final" for 2.0.0. Any
+	// version is accepted by default.
+	Channels []string `toml:"channels"`
+}
+
+var defaultDestinations = []Destination{
+	{Name: "release", Label: " TestFlight ⚙ Beta", Description: "TestFlight and Beta", BranchPrefix: "_release"},
+	{Name: "external", Label: " TestFlight", Description: "TestFlight", BranchPrefix: "_testflight"},
+	{Name: "internal", Label: "⚙ Fabric Beta", Description: "Fabric Beta", BranchPrefix: "_fabric-beta"},
 }
 
 type envConfig struct {
 	BotToken              string `envconfig:"BOT_TOKEN"`
 	VerificationToken     string `envconfig:"VERIFICATION_TOKEN"`
+	SigningSecret         string `envconfig:"SIGNING_SECRET"`
+	SlackMode             string `envconfig:"SLACK_MODE"`
 	BotID                 string `envconfig:"BOT_ID"`
 	ChannelID             string `envconfig:"CHANNEL_ID"`
 	DebugChannelID        string `envconfig:"DEBUG_CHANNEL_ID"`
@@ -33,21 +79,42 @@ type envConfig struct {
 	GitCommitAuthorName   string `envconfig:"GIT_COMMIT_AUTHOR_NAME"`
 	GitCommitAuthorEmail  string `envconfig:"GIT_COMMIT_AUTHOR_EMAIL"`
 	InfoPlistPath         string `envconfig:"INFOPLIST_PATH"`
+	SessionStore          string `envconfig:"SESSION_STORE"`
+	SessionPath           string `envconfig:"SESSION_PATH"`
 }
 
 type tomlConfig struct {
-	BotToken              string `toml:"bot_token"`
-	VerificationToken     string `toml:"verification_token"`
-	BotID                 string `toml:"bot_id"`
-	ChannelID             string `toml:"channel_id"`
-	DebugChannelID        string `toml:"debug_channel_id"`
-	GitHubUsername        string `toml:"github_username"`
-	GitHubToken           string `toml:"github_token"`
-	GitHubRepositoryOwner string `toml:"github_repository_owner"`
-	GitHubRepositoryName  string `toml:"github_repository_name"`
-	GitCommitAuthorName   string `toml:"github_commit_author_name"`
-	GitCommitAuthorEmail  string `toml:"github_commit_author_email"`
-	InfoPlistPath         string `toml:"infoplist_path"`
+	BotToken              string          `toml:"bot_token"`
+	VerificationToken     string          `toml:"verification_token"`
+	SigningSecret         string          `toml:"signing_secret"`
+	SlackMode             string          `toml:"slack_mode"`
+	BotID                 string          `toml:"bot_id"`
+	ChannelID             string          `toml:"channel_id"`
+	DebugChannelID        string          `toml:"debug_channel_id"`
+	GitHubUsername        string          `toml:"github_username"`
+	GitHubToken           string          `toml:"github_token"`
+	GitHubRepositoryOwner string          `toml:"github_repository_owner"`
+	GitHubRepositoryName  string          `toml:"github_repository_name"`
+	GitCommitAuthorName   string          `toml:"github_commit_author_name"`
+	GitCommitAuthorEmail  string          `toml:"github_commit_author_email"`
+	InfoPlistPath         string          `toml:"infoplist_path"`
+	SessionStore          string          `toml:"session_store"`
+	SessionPath           string          `toml:"session_path"`
+	Apps                  []tomlAppConfig `toml:"apps"`
+}
+
+type tomlAppConfig struct {
+	Name                  string              `toml:"name"`
+	GitHubRepositoryOwner string              `toml:"github_repository_owner"`
+	GitHubRepositoryName  string              `toml:"github_repository_name"`
+	VersionFiles          []VersionFileConfig `toml:"version_files"`
+	VersioningScheme      string              `toml:"versioning_scheme"`
+	CalVerFormat          string              `toml:"calver_format"`
+	BuildNumberStrategy   string              `toml:"build_number_strategy"`
+	BuildNumberTagPattern string              `toml:"build_number_tag_pattern"`
+	ReleaseNotes          []ReleaseNoteConfig `toml:"release_notes"`
+	Destinations          []Destination       `toml:"destinations"`
+	ChannelIDs            []string            `toml:"channel_ids"`
 }
 
 func LoadConfig(path, region string) (*Config, error) {
@@ -73,6 +140,26 @@ func LoadConfig(path, region string) (*Config, error) {
 	if env.VerificationToken != "" {
 		config.VerificationToken = env.VerificationToken
 	}
+	config.SigningSecret = tc.SigningSecret
+	if env.SigningSecret != "" {
+		config.SigningSecret = env.SigningSecret
+	}
+	config.SlackMode = tc.SlackMode
+	if env.SlackMode != "" {
+		config.SlackMode = env.SlackMode
+	}
+	if config.SlackMode == "" {
+		// Apps without a signing secret predate the Events API support.
+		config.SlackMode = slackModeEvents
+		if config.SigningSecret == "" {
+			config.SlackMode = slackModeRTM
+		}
+	}
+	if config.SlackMode != slackModeEvents && config.SlackMode != slackModeRTM {
+		err := fmt.Errorf("unknown slack_mode %q (available: %s, %s)", config.SlackMode, slackModeEvents, slackModeRTM)
+		sugar.Errorf("Invalid configuration: %s", err)
+		return nil, err
+	}
 	config.BotID = tc.BotID
 	if env.BotID != "" {
 		config.BotID = env.BotID
@@ -113,10 +200,183 @@ func LoadConfig(path, region string) (*Config, error) {
 	if env.InfoPlistPath != "" {
 		config.InfoPlistPath = env.InfoPlistPath
 	}
+	config.SessionStore = tc.SessionStore
+	if env.SessionStore != "" {
+		config.SessionStore = env.SessionStore
+	}
+	if config.SessionStore == "" {
+		config.SessionStore = defaultSessionStore
+	}
+	if _, ok := sessionStores[config.SessionStore]; !ok {
+		err := fmt.Errorf("unknown session_store %q (available: %s)", config.SessionStore, strings.Join(sessionStoreNames(), ", "))
+		sugar.Errorf("Invalid configuration: %s", err)
+		return nil, err
+	}
+	config.SessionPath = tc.SessionPath
+	if env.SessionPath != "" {
+		config.SessionPath = env.SessionPath
+	}
+	if config.SessionPath == "" {
+		config.SessionPath = defaultSessionPath
+	}
+
+	config.Apps = appConfigs(&config, tc.Apps)
+	if err := validateApps(config.Apps); err != nil {
+		sugar.Errorf("Invalid app configuration: %s", err)
+		return nil, err
+	}
 
 	return &config, nil
 }
 
+// appConfigs builds the app list from the [[apps]] tables. A config without
+// [[apps]] is treated as a single app described by the top-level settings.
+func appConfigs(config *Config, tomlApps []tomlAppConfig) []AppConfig {
+	if len(tomlApps) == 0 {
+		versionFile := VersionFileConfig{Type: "xcconfig", Path: "Configurations/Version.xcconfig"}
+		if config.InfoPlistPath != "" {
+			versionFile.Path = config.InfoPlistPath
+			if !strings.HasSuffix(versionFile.Path, ".xcconfig") {
+				versionFile.Type = "infoplist"
+			}
+		}
+		var channelIDs []string
+		if config.ChannelID != "" {
+			channelIDs = []string{config.ChannelID}
+		}
+		return []AppConfig{
+			{
+				Name:                  config.GitHubRepositoryName,
+				GitHubRepositoryOwner: config.GitHubRepositoryOwner,
+				GitHubRepositoryName:  config.GitHubRepositoryName,
+				VersionFiles:          []VersionFileConfig{versionFile.withDefaults()},
+				VersioningScheme:      defaultVersioningScheme,
+				BuildNumberStrategy:   defaultBuildNumberStrategy,
+				Destinations:          append([]Destination(nil), defaultDestinations...),
+				ChannelIDs:            channelIDs,
+			},
+		}
+	}
+
+	var apps []AppConfig
+	for _, ta := range tomlApps {
+		app := AppConfig{
+			Name:                  ta.Name,
+			GitHubRepositoryOwner: ta.GitHubRepositoryOwner,
+			GitHubRepositoryName:  ta.GitHubRepositoryName,
+			VersionFiles:          ta.VersionFiles,
+			VersioningScheme:      ta.VersioningScheme,
+			CalVerFormat:          ta.CalVerFormat,
+			BuildNumberStrategy:   ta.BuildNumberStrategy,
+			BuildNumberTagPattern: ta.BuildNumberTagPattern,
+			ReleaseNotes:          ta.ReleaseNotes,
+			Destinations:          ta.Destinations,
+			ChannelIDs:            ta.ChannelIDs,
+		}
+		if app.GitHubRepositoryOwner == "" {
+			app.GitHubRepositoryOwner = config.GitHubRepositoryOwner
+		}
+		for i, versionFile := range app.VersionFiles {
+			app.VersionFiles[i] = versionFile.withDefaults()
+		}
+		for i, releaseNote := range app.ReleaseNotes {
+			if releaseNote.Mode == "" {
+				app.ReleaseNotes[i].Mode = releaseNoteReplace
+			}
+		}
+		if app.VersioningScheme == "" {
+			app.VersioningScheme = defaultVersioningScheme
+		}
+		if app.VersioningScheme == "calver" && app.CalVerFormat == "" {
+			app.CalVerFormat = defaultCalVerFormat
+		}
+		if app.BuildNumberStrategy == "" {
+			app.BuildNumberStrategy = defaultBuildNumberStrategy
+		}
+		if app.BuildNumberStrategy == "tags" && app.BuildNumberTagPattern == "" {
+			app.BuildNumberTagPattern = defaultBuildNumberTagPattern
+		}
+		if len(app.Destinations) == 0 {
+			app.Destinations = append([]Destination(nil), defaultDestinations...)
+		}
+		for i, destination := range app.Destinations {
+			if destination.Label == "" {
+				app.Destinations[i].Label = destination.Name
+			}
+			if destination.Description == "" {
+				app.Destinations[i].Description = app.Destinations[i].Label
+			}
+			if destination.BranchPrefix == "" {
+				app.Destinations[i].BranchPrefix = "_" + destination.Name
+			}
+		}
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+func validateApps(apps []AppConfig) error {
+	names := map[string]bool{}
+	for _, app := range apps {
+		if app.Name == "" {
+			return fmt.Errorf("app name is required")
+		}
+		if strings.ContainsAny(app.Name, " \t") {
+			return fmt.Errorf("app name %q must not contain whitespace", app.Name)
+		}
+		if names[app.Name] {
+			return fmt.Errorf("duplicate app name %q", app.Name)
+		}
+		names[app.Name] = true
+
+		if app.GitHubRepositoryOwner == "" || app.GitHubRepositoryName == "" {
+			return fmt.Errorf("app %q: github_repository_owner and github_repository_name are required", app.Name)
+		}
+		if len(app.VersionFiles) == 0 {
+			return fmt.Errorf("app %q: version_files is required", app.Name)
+		}
+		for _, versionFile := range app.VersionFiles {
+			if err := versionFile.validate(); err != nil {
+				return fmt.Errorf("app %q: %s", app.Name, err)
+			}
+		}
+		if _, ok := versioningSchemes[app.VersioningScheme]; !ok {
+			return fmt.Errorf("app %q: unknown versioning scheme %q (available: %v)", app.Name, app.VersioningScheme, versioningSchemeNames())
+		}
+		if app.VersioningScheme == "calver" {
+			if _, err := parseCalVerFormat(app.CalVerFormat); err != nil {
+				return fmt.Errorf("app %q: %s", app.Name, err)
+			}
+		} else if app.CalVerFormat != "" {
+			return fmt.Errorf("app %q: calver_format is only supported by the calver versioning scheme", app.Name)
+		}
+		if err := validateBuildNumberStrategy(app.BuildNumberStrategy, app.BuildNumberTagPattern); err != nil {
+			return fmt.Errorf("app %q: %s", app.Name, err)
+		}
+		for _, releaseNote := range app.ReleaseNotes {
+			if err := releaseNote.validate(); err != nil {
+				return fmt.Errorf("app %q: %s", app.Name, err)
+			}
+		}
+		destinations := map[string]bool{}
+		for _, destination := range app.Destinations {
+			if destination.Name == "" {
+				return fmt.Errorf("app %q: destination name is required", app.Name)
+			}
+			if destinations[destination.Name] {
+				return fmt.Errorf("app %q: duplicate destination %q", app.Name, destination.Name)
+			}
+			destinations[destination.Name] = true
+			for _, channel := range destination.Channels {
+				if err := validateChannel(channel); err != nil {
+					return fmt.Errorf("app %q: destination %q: %s", app.Name, destination.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func loadToml(path, region string) (*tomlConfig, error) {
 	var config tomlConfig
 	if _, err := toml.DecodeFile(path, &config, region); err != nil {
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfigFile writes the config to a temporary file and returns its path.
+func testConfigFile(t *testing.T, config string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigApps(t *testing.T) {
+	path := testConfigFile(t, `
+github_repository_owner = "owner"
+
+[[apps]]
+name                   = "ios"
+github_repository_name = "ios-repository"
+channel_ids            = ["C1"]
+
+[[apps.version_files]]
+type = "infoplist"
+path = "App/Info.plist"
+
+[[apps.destinations]]
+name = "testflight"
+
+[[apps]]
+name                    = "android"
+github_repository_owner = "android-owner"
+github_repository_name  = "android-repository"
+
+[[apps.version_files]]
+type = "infoplist"
+path = "App/Info.plist"
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Apps) != 2 {
+		t.Fatalf("got %d apps, want 2", len(config.Apps))
+	}
+
+	ios, android := config.Apps[0], config.Apps[1]
+	if ios.Name != "ios" || ios.GitHubRepositoryOwner != "owner" || ios.GitHubRepositoryName != "ios-repository" {
+		t.Errorf("ios = %s %s/%s", ios.Name, ios.GitHubRepositoryOwner, ios.GitHubRepositoryName)
+	}
+	if len(ios.ChannelIDs) != 1 || ios.ChannelIDs[0] != "C1" {
+		t.Errorf("ios channels = %q", ios.ChannelIDs)
+	}
+	if len(ios.Destinations) != 1 {
+		t.Fatalf("ios destinations = %v", ios.Destinations)
+	}
+	if destination := ios.Destinations[0]; destination.Label != "testflight" || destination.Description != "testflight" || destination.BranchPrefix != "_testflight" {
+		t.Errorf("ios destination = %+v", destination)
+	}
+
+	if android.GitHubRepositoryOwner != "android-owner" {
+		t.Errorf("android owner = %s", android.GitHubRepositoryOwner)
+	}
+	if len(android.Destinations) != len(defaultDestinations) {
+		t.Errorf("android destinations = %v, want the defaults", android.Destinations)
+	}
+}
+
+func TestLoadConfigSingleApp(t *testing.T) {
+	path := testConfigFile(t, `
+channel_id              = "C1"
+github_repository_owner = "owner"
+github_repository_name  = "repository"
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Apps) != 1 {
+		t.Fatalf("got %d apps, want 1", len(config.Apps))
+	}
+	app := config.Apps[0]
+	if app.Name != "repository" || app.GitHubRepositoryOwner != "owner" {
+		t.Errorf("app = %s %s/%s", app.Name, app.GitHubRepositoryOwner, app.GitHubRepositoryName)
+	}
+	if len(app.ChannelIDs) != 1 || app.ChannelIDs[0] != "C1" {
+		t.Errorf("channels = %q", app.ChannelIDs)
+	}
+}
+
+func TestLoadConfigInvalidApps(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "no name",
+			config: "[[apps]]\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\n",
+			want:   "app name is required",
+		},
+		{
+			name:   "whitespace in name",
+			config: "[[apps]]\nname = \"ios main\"\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\n",
+			want:   "must not contain whitespace",
+		},
+		{
+			name:   "duplicate name",
+			config: "[[apps]]\nname = \"ios\"\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\n[[apps.version_files]]\ntype = \"infoplist\"\npath = \"App/Info.plist\"\n[[apps]]\nname = \"ios\"\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\n",
+			want:   "duplicate app name",
+		},
+		{
+			name:   "calver format of semver",
+			config: "[[apps]]\nname = \"ios\"\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\ncalver_format = \"YY.0M.MICRO\"\n[[apps.version_files]]\ntype = \"infoplist\"\npath = \"App/Info.plist\"\n",
+			want:   "calver_format is only supported by the calver versioning scheme",
+		},
+		{
+			name:   "invalid calver format",
+			config: "[[apps]]\nname = \"ios\"\ngithub_repository_owner = \"owner\"\ngithub_repository_name = \"repository\"\nversioning_scheme = \"calver\"\ncalver_format = \"YY.MM.PATCH\"\n[[apps.version_files]]\ntype = \"infoplist\"\npath = \"App/Info.plist\"\n",
+			want:   "must be YEAR.MONTH.MICRO",
+		},
+		{
+			name:   "no repository",
+			config: "[[apps]]\nname = \"ios\"\n",
+			want:   "github_repository_owner and github_repository_name are required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := testConfigFile(t, test.config)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			_, err := LoadConfig(path, "")
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("LoadConfig() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
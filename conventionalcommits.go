@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	bumpMajor = "major"
+	bumpMinor = "minor"
+	bumpPatch = "patch"
+)
+
+// conventionalCommitPattern matches the header of a Conventional Commit such
+// as `feat(parser)!: add arrays`.
+var conventionalCommitPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\([^)]*\))?(!)?: `)
+
+var breakingChangePattern = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+// commitSummary counts commits by Conventional Commit type.
+type commitSummary struct {
+	breaking int
+	feat     int
+	fix      int
+}
+
+func summarizeCommits(messages []string) commitSummary {
+	var summary commitSummary
+	for _, message := range messages {
+		m := conventionalCommitPattern.FindStringSubmatch(message)
+		if m == nil {
+			continue
+		}
+		if m[2] != "" || breakingChangePattern.MatchString(message) {
+			summary.breaking++
+		}
+		switch strings.ToLower(m[1]) {
+		case "feat":
+			summary.feat++
+		case "fix":
+			summary.fix++
+		}
+	}
+	return summary
+}
+
+// bump returns the version component the commits call for to be bumped, or an
+// empty string if there is nothing to release.
+func (summary commitSummary) bump() string {
+	switch {
+	case summary.breaking > 0:
+		return bumpMajor
+	case summary.feat > 0:
+		return bumpMinor
+	case summary.fix > 0:
+		return bumpPatch
+	default:
+		return ""
+	}
+}
+
+// String explains the bump, e.g. "3 feat, 5 fix → minor".
+func (summary commitSummary) String() string {
+	var counts []string
+	if summary.breaking > 0 {
+		counts = append(counts, fmt.Sprintf("%d BREAKING CHANGE", summary.breaking))
+	}
+	if summary.feat > 0 {
+		counts = append(counts, fmt.Sprintf("%d feat", summary.feat))
+	}
+	if summary.fix > 0 {
+		counts = append(counts, fmt.Sprintf("%d fix", summary.fix))
+	}
+	return fmt.Sprintf("%s → %s", strings.Join(counts, ", "), summary.bump())
+}
+
+// commitsSinceLatestTag returns the messages of the commits on the branch
+// since the latest tag.
+func commitsSinceLatestTag(service *GitHubService, branch string) ([]string, error) {
+	tag, err := service.LatestTag(branch)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := service.Commits(tag.GetName(), branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, commit := range commits {
+		messages = append(messages, commit.GetCommit().GetMessage())
+	}
+	return messages, nil
+}
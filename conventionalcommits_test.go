@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		bump     string
+		summary  string
+	}{
+		{
+			name:     "feat",
+			messages: []string{"feat: add arrays", "fix: handle nil", "fix(parser): skip comments"},
+			bump:     bumpMinor,
+			summary:  "1 feat, 2 fix → minor",
+		},
+		{
+			name:     "fix",
+			messages: []string{"fix: handle nil", "docs: update README", "chore: bump deps"},
+			bump:     bumpPatch,
+			summary:  "1 fix → patch",
+		},
+		{
+			name:     "breaking change marker",
+			messages: []string{"feat(api)!: remove v1", "fix: handle nil"},
+			bump:     bumpMajor,
+			summary:  "1 BREAKING CHANGE, 1 feat, 1 fix → major",
+		},
+		{
+			name:     "breaking change footer",
+			messages: []string{"refactor: rename options\n\nBREAKING CHANGE: Options is now Config."},
+			bump:     bumpMajor,
+			summary:  "1 BREAKING CHANGE → major",
+		},
+		{
+			name:     "breaking change footer with a hyphen",
+			messages: []string{"fix: parse dates\n\nBREAKING-CHANGE: dates are UTC."},
+			bump:     bumpMajor,
+			summary:  "1 BREAKING CHANGE, 1 fix → major",
+		},
+		{
+			name:     "upper case type",
+			messages: []string{"Feat: add arrays"},
+			bump:     bumpMinor,
+			summary:  "1 feat → minor",
+		},
+		{
+			// Messages that are not Conventional Commits are ignored, even
+			// if they mention a type or a breaking change.
+			name:     "not conventional",
+			messages: []string{"Add arrays", "feat add arrays", "Merge pull request #12 from owner/feat: arrays", "fix:no space", "Update README\n\nBREAKING CHANGE: none"},
+		},
+		{
+			name: "no commits",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summary := summarizeCommits(test.messages)
+			if got := summary.bump(); got != test.bump {
+				t.Errorf("bump() = %q, want %q", got, test.bump)
+			}
+			if test.bump != "" {
+				if got := summary.String(); got != test.summary {
+					t.Errorf("String() = %q, want %q", got, test.summary)
+				}
+			}
+		})
+	}
+}
+
+// testCommitsService returns a GitHubService with the tag v1.2.3 followed by
+// commits with the messages on main.
+func testCommitsService(t *testing.T, messages []string) *GitHubService {
+	return testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repository/tags":
+			w.Write([]byte(`[{"name": "v1.2.3", "commit": {"sha": "a1"}}, {"name": "latest", "commit": {"sha": "a0"}}]`))
+		case strings.HasPrefix(r.URL.Path, "/repos/owner/repository/compare/"):
+			var commits []map[string]interface{}
+			for _, message := range messages {
+				commits = append(commits, map[string]interface{}{"commit": map[string]string{"message": message}})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "ahead", "commits": commits})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRecommendVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		messages []string
+		version  string
+		reason   string
+	}{
+		{name: "feat", scheme: "semver", messages: []string{"feat: add arrays", "fix: handle nil"}, version: "1.3.0", reason: "1 feat, 1 fix → minor"},
+		{name: "fix", scheme: "semver", messages: []string{"fix: handle nil"}, version: "1.2.4", reason: "1 fix → patch"},
+		{name: "breaking", scheme: "semver", messages: []string{"feat!: remove v1"}, version: "2.0.0", reason: "1 BREAKING CHANGE, 1 feat → major"},
+		{name: "not conventional", scheme: "semver", messages: []string{"Add arrays"}},
+		// Calendar versions have no major, minor or patch components.
+		{name: "calver", scheme: "calver", messages: []string{"feat: add arrays"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{Service: testCommitsService(t, test.messages), VersioningScheme: test.scheme}
+			versionFile := testVersionFile(t, "1.2.3")
+			nextVersions, err := nextVersions(versionFile, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version, reason := recommendVersion(app, versioningSchemes[test.scheme], versionFile, "main", nextVersions)
+			if version != test.version || reason != test.reason {
+				t.Errorf("recommendVersion() = %q, %q, want %q, %q", version, reason, test.version, test.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// deliveryTTL is how long handled interactions and events are remembered.
+// Slack retries events for a few minutes at most.
+const deliveryTTL = 10 * time.Minute
+
+// deliveries remembers the interactions and events handled recently, so that
+// ones delivered again are handled once.
+type deliveries struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	ttl  time.Duration
+}
+
+func newDeliveries(ttl time.Duration) *deliveries {
+	return &deliveries{seen: map[string]time.Time{}, ttl: ttl}
+}
+
+// first reports whether key is delivered for the first time in the TTL.
+func (d *deliveries) first(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, t := range d.seen {
+		if now.Sub(t) > d.ttl {
+			delete(d.seen, k)
+		}
+	}
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// forget forgets key, so that it is handled again when it is delivered again.
+func (d *deliveries) forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.seen, key)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveries(t *testing.T) {
+	now := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	d := newDeliveries(time.Minute)
+
+	if !d.first("view/V1/form", now) {
+		t.Error("the first delivery is a duplicate")
+	}
+	if d.first("view/V1/form", now.Add(time.Second)) {
+		t.Error("the second delivery is not a duplicate")
+	}
+	d.forget("view/V1/form")
+	if !d.first("view/V1/form", now.Add(2*time.Second)) {
+		t.Error("a forgotten delivery is a duplicate")
+	}
+	if !d.first("view/V1/form", now.Add(2*time.Minute)) {
+		t.Error("an expired delivery is a duplicate")
+	}
+}
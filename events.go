@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// eventsHandler receives events from the Slack Events API.
+type eventsHandler struct {
+	verifier   slackVerifier
+	listener   *SlackListener
+	deliveries *deliveries
+}
+
+type eventsPayload struct {
+	Type      string          `json:"type"`
+	Token     string          `json:"token"`
+	Challenge string          `json:"challenge"`
+	EventID   string          `json:"event_id"`
+	Event     json.RawMessage `json:"event"`
+}
+
+func (h eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sugar.Errorf("Invalid method: %s", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sugar.Errorf("Failed to read request body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := h.verifier.verifySignature(r.Header, body); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var payload eventsPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		sugar.Errorf("Failed to decode event from slack: %s", body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := h.verifier.verifyToken(payload.Token); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch payload.Type {
+	case "url_verification":
+		w.Header().Add("Content-type", "text/plain")
+		w.Write([]byte(payload.Challenge))
+	case "event_callback":
+		// The event is handled after the response since Slack retries events
+		// that are not acknowledged within 3 seconds.
+		w.WriteHeader(http.StatusOK)
+		if !h.deliveries.first("event/"+payload.EventID, time.Now()) {
+			sugar.Infof("Ignoring retry %s of event %s", r.Header.Get("X-Slack-Retry-Num"), payload.EventID)
+			return
+		}
+
+		var ev slack.MessageEvent
+		if err := json.Unmarshal(payload.Event, &ev); err != nil {
+			sugar.Errorf("Failed to decode event from slack: %s", payload.Event)
+			return
+		}
+		if ev.Type != "app_mention" && !(ev.Type == "message" && isDirectMessage(ev.Channel)) {
+			return
+		}
+		go func() {
+			if err := h.listener.handleMessageEvent(&ev); err != nil {
+				sugar.Errorf("Failed to handle message: %s", err)
+			}
+		}()
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
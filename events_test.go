@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsHandlerURLVerification(t *testing.T) {
+	body := `{"token": "legacy", "challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P", "type": "url_verification"}`
+
+	tests := []struct {
+		name          string
+		signingSecret string
+		header        http.Header
+		body          string
+		status        int
+		response      string
+	}{
+		{name: "signed", signingSecret: testSigningSecret, header: testSignedHeader(body), body: body, status: http.StatusOK, response: "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"},
+		{name: "unsigned", signingSecret: testSigningSecret, header: http.Header{}, body: body, status: http.StatusUnauthorized},
+		{name: "tampered", signingSecret: testSigningSecret, header: testSignedHeader(body), body: strings.Replace(body, "3eZ", "4eZ", 1), status: http.StatusUnauthorized},
+		{name: "token", header: http.Header{}, body: body, status: http.StatusOK, response: "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"},
+		{name: "invalid token", header: http.Header{}, body: strings.Replace(body, "legacy", "other", 1), status: http.StatusUnauthorized},
+		{name: "invalid JSON", header: http.Header{}, body: "challenge", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := eventsHandler{
+				verifier:   slackVerifier{signingSecret: test.signingSecret, verificationToken: "legacy", now: func() time.Time { return testRequestTime }},
+				deliveries: newDeliveries(time.Hour),
+			}
+			r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(test.body))
+			r.Header = test.header
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d", w.Code, test.status)
+			}
+			if got := w.Body.String(); got != test.response {
+				t.Errorf("response = %q, want %q", got, test.response)
+			}
+		})
+	}
+}
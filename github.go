@@ -6,16 +6,17 @@ import (
 	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
 	"io/ioutil"
-	"log"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 )
 
 type GitHubService struct {
-	Repository    GitHubRepository
-	Author        CommitAuthor
-	InfoPlistPath string
-	Client        *github.Client
+	Repository GitHubRepository
+	Author     CommitAuthor
+	Client     *github.Client
 }
 
 type GitHubRepository struct {
@@ -31,13 +32,12 @@ type CommitAuthor struct {
 type PullRequest struct {
 	TargetBranch  string
 	CommitBranch  string
-	FileContent   []byte
-	FilePath      string
+	Files         map[string][]byte
 	Title         string
 	CommitMessage string
 }
 
-func NewGitHubService(token string, repo GitHubRepository, author CommitAuthor, infoPlistPath string) *GitHubService {
+func NewGitHubService(token string, repo GitHubRepository, author CommitAuthor) *GitHubService {
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
@@ -46,10 +46,9 @@ func NewGitHubService(token string, repo GitHubRepository, author CommitAuthor,
 	client := github.NewClient(tc)
 
 	return &GitHubService{
-		Repository:    repo,
-		Author:        author,
-		Client:        client,
-		InfoPlistPath: infoPlistPath,
+		Repository: repo,
+		Author:     author,
+		Client:     client,
 	}
 }
 
@@ -88,6 +87,21 @@ func (g *GitHubService) File(branch, path string) ([]byte, error) {
 	return bytes, nil
 }
 
+// Directory returns the entries of a directory. It returns no entries if the
+// directory does not exist.
+func (g *GitHubService) Directory(branch, path string) ([]*github.RepositoryContent, error) {
+	_, entries, resp, err := g.Client.Repositories.GetContents(context.Background(), g.Repository.Owner, g.Repository.Name, path, &github.RepositoryContentGetOptions{
+		Ref: branch,
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub directory %s: %s", path, err)
+	}
+	return entries, nil
+}
+
 func filter(vs []*github.Branch, f func(github.Branch) bool) []github.Branch {
 	vsf := make([]github.Branch, 0)
 	for _, v := range vs {
@@ -116,9 +130,17 @@ func (g *GitHubService) CreateBranch(from, to string) (ref *github.Reference, er
 
 // GetTree generates the tree to commit based on the given files and the commit
 // of the ref you got in getRef.
-func (g *GitHubService) CreateTree(ref *github.Reference, content []byte, path string) (tree *github.Tree, err error) {
+func (g *GitHubService) CreateTree(ref *github.Reference, files map[string][]byte) (tree *github.Tree, err error) {
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
 	entries := []github.TreeEntry{}
-	entries = append(entries, github.TreeEntry{Path: github.String(path), Type: github.String("blob"), Content: github.String(string(content)), Mode: github.String("100644")})
+	for _, path := range paths {
+		entries = append(entries, github.TreeEntry{Path: github.String(path), Type: github.String("blob"), Content: github.String(string(files[path])), Mode: github.String("100644")})
+	}
 	tree, _, err = g.Client.Git.CreateTree(context.Background(), g.Repository.Owner, g.Repository.Name, *ref.Object.SHA, entries)
 	return tree, err
 }
@@ -177,10 +199,10 @@ func (g *GitHubService) PushPullRequest(pullRequest PullRequest) (*string, error
 	}
 	if ref == nil {
 		sugar.Errorf("No error where returned but the reference is nil")
-		return nil, err
+		return nil, fmt.Errorf("unable to get/create the commit reference")
 	}
 
-	tree, err := g.CreateTree(ref, pullRequest.FileContent, pullRequest.FilePath)
+	tree, err := g.CreateTree(ref, pullRequest.Files)
 	if err != nil {
 		sugar.Errorf("Unable to create the tree based on the provided files: %s\n", err)
 		return nil, err
@@ -193,7 +215,7 @@ func (g *GitHubService) PushPullRequest(pullRequest PullRequest) (*string, error
 
 	pr, err := g.CreatePullRequest(pullRequest.TargetBranch, pullRequest.CommitBranch, pullRequest.Title, pullRequest.CommitMessage)
 	if err != nil {
-		log.Fatalf("Error while creating the pull request: %s", err)
+		sugar.Errorf("Unable to create the pull request: %s\n", err)
 		return nil, err
 	}
 
@@ -201,21 +223,134 @@ func (g *GitHubService) PushPullRequest(pullRequest PullRequest) (*string, error
 	return &u, nil
 }
 
-func (g *GitHubService) LatestTag() (*github.RepositoryTag, error) {
-	tags, _, err := g.Client.Repositories.ListTags(context.Background(), g.Repository.Owner, g.Repository.Name, &github.ListOptions{})
+// LatestTag returns the tag with the highest version that is reachable from
+// the branch.
+func (g *GitHubService) LatestTag(branch string) (*github.RepositoryTag, error) {
+	tags, err := g.Tags()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch GitHub tags: %s", err)
+		return nil, err
 	}
-	if len(tags) == 0 {
-		return nil, fmt.Errorf("no tags")
+
+	var releases []*github.RepositoryTag
+	for _, tag := range tags {
+		if _, ok := tagVersion(tag.GetName()); ok {
+			releases = append(releases, tag)
+		}
 	}
-	return tags[0], nil
+	sort.SliceStable(releases, func(i, j int) bool {
+		vi, _ := tagVersion(releases[i].GetName())
+		vj, _ := tagVersion(releases[j].GetName())
+		c, _ := compareVersions(vi, vj)
+		return c > 0
+	})
+
+	for _, tag := range releases {
+		comparison, _, err := g.Client.Repositories.CompareCommits(context.Background(), g.Repository.Owner, g.Repository.Name, tag.GetCommit().GetSHA(), url.PathEscape(branch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s with %s: %s", tag.GetName(), branch, err)
+		}
+		if status := comparison.GetStatus(); status == "ahead" || status == "identical" {
+			return tag, nil
+		}
+	}
+	return nil, fmt.Errorf("no release tags are reachable from %s", branch)
 }
 
+// Tags returns all tags in the repository.
+func (g *GitHubService) Tags() ([]*github.RepositoryTag, error) {
+	var tags []*github.RepositoryTag
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := g.Client.Repositories.ListTags(context.Background(), g.Repository.Owner, g.Repository.Name, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch GitHub tags: %s", err)
+		}
+		tags = append(tags, page...)
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// PullRequests calls fn with the pages of the pull requests into the base
+// branch in the state: "open", "closed" or "all", newest first. Paging stops
+// when fn returns false.
+func (g *GitHubService) PullRequests(state string, base string, fn func([]*github.PullRequest) bool) error {
+	opt := &github.PullRequestListOptions{State: state, Base: base, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := g.Client.PullRequests.List(context.Background(), g.Repository.Owner, g.Repository.Name, opt)
+		if err != nil {
+			return fmt.Errorf("failed to fetch GitHub pull requests: %s", err)
+		}
+		if !fn(page) || resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// CommitCount returns the number of commits in the history of the branch.
+func (g *GitHubService) CommitCount(branch string) (int, error) {
+	commits, resp, err := g.Client.Repositories.ListCommits(context.Background(), g.Repository.Owner, g.Repository.Name, &github.CommitsListOptions{
+		SHA:         branch,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch GitHub commits: %s", err)
+	}
+	// With one commit per page, the number of the last page is the number of
+	// commits.
+	if resp.LastPage > 0 {
+		return resp.LastPage, nil
+	}
+	return len(commits), nil
+}
+
+// Commits returns the commits reachable from head but not from base, oldest
+// first. A comparison returns up to 250 commits, so its pages are followed.
 func (g *GitHubService) Commits(base string, head string) ([]github.RepositoryCommit, error) {
-	commitsComparison, _, err := g.Client.Repositories.CompareCommits(context.Background(), g.Repository.Owner, g.Repository.Name, base, head)
+	var commits []github.RepositoryCommit
+	for page := 1; page != 0; {
+		u := fmt.Sprintf("repos/%v/%v/compare/%v...%v?per_page=100&page=%d", g.Repository.Owner, g.Repository.Name, url.PathEscape(base), url.PathEscape(head), page)
+		req, err := g.Client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		var comparison github.CommitsComparison
+		resp, err := g.Client.Do(context.Background(), req, &comparison)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch GitHub commits: %s", err)
+		}
+		commits = append(commits, comparison.Commits...)
+		page = resp.NextPage
+	}
+	return commits, nil
+}
+
+// Issue returns the issue or pull request. Unlike a pull request, an issue has
+// labels.
+func (g *GitHubService) Issue(number int) (*github.Issue, error) {
+	issue, _, err := g.Client.Issues.Get(context.Background(), g.Repository.Owner, g.Repository.Name, number)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch GitHub commits: %s", err)
+		return nil, fmt.Errorf("failed to fetch GitHub issue #%d: %s", number, err)
+	}
+	return issue, nil
+}
+
+// PullRequestCommits returns the commits of the pull request.
+func (g *GitHubService) PullRequestCommits(number int) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := g.Client.PullRequests.ListCommits(context.Background(), g.Repository.Owner, g.Repository.Name, number, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commits of GitHub pull request #%d: %s", number, err)
+		}
+		commits = append(commits, page...)
+		if resp.NextPage == 0 {
+			return commits, nil
+		}
+		opt.Page = resp.NextPage
 	}
-	return commitsComparison.Commits, nil
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// testGitHubService returns a GitHubService of owner/repository that sends
+// its requests to handler. The server is closed at the end of the test.
+func testGitHubService(t *testing.T, handler http.Handler) *GitHubService {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return &GitHubService{
+		Repository: GitHubRepository{Owner: "owner", Name: "repository"},
+		Author:     CommitAuthor{Name: "deliverbot", Email: "deliverbot@example.com"},
+		Client:     client,
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	gradleVersionKey     = "versionName"
+	gradleBuildNumberKey = "versionCode"
+)
+
+const (
+	gradleIdentifier = iota
+	gradleString
+	gradleNumber
+	gradlePunctuation
+)
+
+// gradleToken is a token of a Groovy or Kotlin build script. For strings,
+// start and end are the offsets of the contents without the quotes.
+type gradleToken struct {
+	kind  int
+	text  string
+	start int
+	end   int
+}
+
+// tokenizeGradle splits a build script into identifiers, literals and
+// punctuation, skipping whitespace and comments. It understands just enough
+// of Groovy and Kotlin to find blocks and assignments.
+func tokenizeGradle(src []byte) ([]gradleToken, error) {
+	var tokens []gradleToken
+	pos := 0
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';':
+			pos++
+		case bytes.HasPrefix(src[pos:], []byte("//")):
+			if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+				pos += i + 1
+			} else {
+				pos = len(src)
+			}
+		case bytes.HasPrefix(src[pos:], []byte("/*")):
+			i := bytes.Index(src[pos+2:], []byte("*/"))
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			pos += i + 4
+		case c == '"' || c == '\'':
+			quote := []byte{c}
+			if bytes.HasPrefix(src[pos:], []byte{c, c, c}) {
+				quote = []byte{c, c, c}
+			}
+			start := pos + len(quote)
+			end := start
+			for {
+				if end >= len(src) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if src[end] == '\\' {
+					end += 2
+					continue
+				}
+				if bytes.HasPrefix(src[end:], quote) {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, gradleToken{kind: gradleString, text: string(src[start:end]), start: start, end: end})
+			pos = end + len(quote)
+		case c >= '0' && c <= '9':
+			start := pos
+			for pos < len(src) && (isGradleIdentifier(src[pos]) || src[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, gradleToken{kind: gradleNumber, text: string(src[start:pos]), start: start, end: pos})
+		case isGradleIdentifier(c):
+			start := pos
+			for pos < len(src) && isGradleIdentifier(src[pos]) {
+				pos++
+			}
+			tokens = append(tokens, gradleToken{kind: gradleIdentifier, text: string(src[start:pos]), start: start, end: pos})
+		default:
+			tokens = append(tokens, gradleToken{kind: gradlePunctuation, text: string(c), start: pos, end: pos + 1})
+			pos++
+		}
+	}
+	return tokens, nil
+}
+
+func isGradleIdentifier(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$'
+}
+
+func (token gradleToken) is(kind int, text string) bool {
+	return token.kind == kind && token.text == text
+}
+
+// gradleBlockEnd returns the index of the `}` that closes the `{` at open.
+func gradleBlockEnd(tokens []gradleToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].is(gradlePunctuation, "{") {
+			depth++
+		} else if tokens[i].is(gradlePunctuation, "}") {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// findGradleBlock returns the token range of the body of the first block
+// named name between from and to, at any depth. Both `name {` and the Kotlin
+// forms `create("name") {`, `register("name") {` and `getByName("name") {`
+// are recognized.
+func findGradleBlock(tokens []gradleToken, from, to int, name string) (int, int, bool) {
+	for i := from; i < to; i++ {
+		open := -1
+		switch {
+		case tokens[i].is(gradleIdentifier, name) && i+1 < to && tokens[i+1].is(gradlePunctuation, "{"):
+			open = i + 1
+		case tokens[i].kind == gradleIdentifier && i+4 < to &&
+			(tokens[i].text == "create" || tokens[i].text == "register" || tokens[i].text == "getByName" || tokens[i].text == "maybeCreate") &&
+			tokens[i+1].is(gradlePunctuation, "(") && tokens[i+2].is(gradleString, name) &&
+			tokens[i+3].is(gradlePunctuation, ")") && tokens[i+4].is(gradlePunctuation, "{"):
+			open = i + 4
+		}
+		if open >= 0 {
+			return open + 1, gradleBlockEnd(tokens, open), true
+		}
+	}
+	return 0, 0, false
+}
+
+// Gradle is a version file that keeps the version and build number in the
+// defaultConfig or a product flavor of build.gradle or build.gradle.kts.
+type Gradle struct {
+	path           string
+	raw            []byte
+	versionKey     string
+	buildNumberKey string
+	values         map[string]gradleToken
+	updates        map[string]string
+}
+
+func NewGradle(config VersionFileConfig, raw []byte) (*Gradle, error) {
+	tokens, err := tokenizeGradle(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := 0, len(tokens)
+	if config.Flavor != "" {
+		var ok bool
+		if from, to, ok = findGradleBlock(tokens, from, to, "productFlavors"); !ok {
+			return nil, fmt.Errorf("productFlavors is not found")
+		}
+		if from, to, ok = findGradleBlock(tokens, from, to, config.Flavor); !ok {
+			return nil, fmt.Errorf("product flavor %q is not found", config.Flavor)
+		}
+	} else if f, t, ok := findGradleBlock(tokens, from, to, "defaultConfig"); ok {
+		from, to = f, t
+	}
+
+	gradle := Gradle{
+		path:           config.Path,
+		raw:            raw,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		values:         map[string]gradleToken{},
+		updates:        map[string]string{},
+	}
+
+	for _, key := range []string{config.VersionKey, config.BuildNumberKey} {
+		value, err := gradleAssignment(tokens, from, to, key)
+		if err != nil {
+			return nil, err
+		}
+		gradle.values[key] = value
+	}
+
+	return &gradle, nil
+}
+
+// gradleAssignment returns the literal value of `key value`, `key = value` or
+// `key(value)` directly in the block between from and to.
+func gradleAssignment(tokens []gradleToken, from, to int, key string) (gradleToken, error) {
+	depth := 0
+	for i := from; i < to; i++ {
+		switch {
+		case tokens[i].is(gradlePunctuation, "{"):
+			depth++
+		case tokens[i].is(gradlePunctuation, "}"):
+			depth--
+		case depth == 0 && tokens[i].is(gradleIdentifier, key):
+			j := i + 1
+			if j < to && (tokens[j].is(gradlePunctuation, "=") || tokens[j].is(gradlePunctuation, "(")) {
+				j++
+			}
+			if j >= to || (tokens[j].kind != gradleString && tokens[j].kind != gradleNumber) || strings.Contains(tokens[j].text, "$") {
+				return gradleToken{}, fmt.Errorf("%s is not a literal", key)
+			}
+			return tokens[j], nil
+		}
+	}
+	return gradleToken{}, fmt.Errorf("%s is not found", key)
+}
+
+func (gradle *Gradle) value(key string) string {
+	if value, ok := gradle.updates[key]; ok {
+		return value
+	}
+	return gradle.values[key].text
+}
+
+func (gradle *Gradle) Version() string {
+	return gradle.value(gradle.versionKey)
+}
+
+func (gradle *Gradle) BuildNumber() string {
+	return gradle.value(gradle.buildNumberKey)
+}
+
+func (gradle *Gradle) NextMajor() (string, error) {
+	return nextMajor(gradle.Version())
+}
+
+func (gradle *Gradle) NextMinor() (string, error) {
+	return nextMinor(gradle.Version())
+}
+
+func (gradle *Gradle) NextPatch() (string, error) {
+	return nextPatch(gradle.Version())
+}
+
+func (gradle *Gradle) NextBuildNumber() (string, error) {
+	return nextBuildNumber(gradle.BuildNumber())
+}
+
+func (gradle *Gradle) SetVersion(version string, build string) {
+	gradle.updates[gradle.versionKey] = version
+	gradle.updates[gradle.buildNumberKey] = build
+}
+
+func (gradle *Gradle) Files() (map[string][]byte, error) {
+	var edits []textEdit
+	for key, value := range gradle.updates {
+		token := gradle.values[key]
+		if token.kind == gradleNumber && !gradleNumberPattern.MatchString(value) {
+			return nil, fmt.Errorf("%s must be a number: %s", key, value)
+		}
+		edits = append(edits, textEdit{start: token.start, end: token.end, text: value})
+	}
+	return map[string][]byte{gradle.path: applyTextEdits(gradle.raw, edits)}, nil
+}
+
+var gradleNumberPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// gradlePropertyPattern matches `key=value`, `key = value` and `key: value`.
+var gradlePropertyPattern = regexp.MustCompile(`^[ \t]*([^#!=:\s]+)[ \t]*[=:][ \t]*(.*?)[ \t]*$`)
+
+// GradleProperties is a version file that keeps the version and build number
+// in gradle.properties.
+type GradleProperties struct {
+	path           string
+	raw            []byte
+	versionKey     string
+	buildNumberKey string
+	values         map[string]textEdit
+	updates        map[string]string
+}
+
+func NewGradleProperties(config VersionFileConfig, raw []byte) (*GradleProperties, error) {
+	properties := GradleProperties{
+		path:           config.Path,
+		raw:            raw,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		values:         map[string]textEdit{},
+		updates:        map[string]string{},
+	}
+
+	offset := 0
+	for _, line := range bytes.SplitAfter(raw, []byte("\n")) {
+		content := bytes.TrimRight(line, "\r\n")
+		if m := gradlePropertyPattern.FindSubmatchIndex(content); m != nil {
+			key := string(content[m[2]:m[3]])
+			properties.values[key] = textEdit{start: offset + m[4], end: offset + m[5], text: string(content[m[4]:m[5]])}
+		}
+		offset += len(line)
+	}
+
+	for _, key := range []string{config.VersionKey, config.BuildNumberKey} {
+		if _, ok := properties.values[key]; !ok {
+			return nil, fmt.Errorf("%s is not found", key)
+		}
+	}
+
+	return &properties, nil
+}
+
+func (properties *GradleProperties) value(key string) string {
+	if value, ok := properties.updates[key]; ok {
+		return value
+	}
+	return properties.values[key].text
+}
+
+func (properties *GradleProperties) Version() string {
+	return properties.value(properties.versionKey)
+}
+
+func (properties *GradleProperties) BuildNumber() string {
+	return properties.value(properties.buildNumberKey)
+}
+
+func (properties *GradleProperties) NextMajor() (string, error) {
+	return nextMajor(properties.Version())
+}
+
+func (properties *GradleProperties) NextMinor() (string, error) {
+	return nextMinor(properties.Version())
+}
+
+func (properties *GradleProperties) NextPatch() (string, error) {
+	return nextPatch(properties.Version())
+}
+
+func (properties *GradleProperties) NextBuildNumber() (string, error) {
+	return nextBuildNumber(properties.BuildNumber())
+}
+
+func (properties *GradleProperties) SetVersion(version string, build string) {
+	properties.updates[properties.versionKey] = version
+	properties.updates[properties.buildNumberKey] = build
+}
+
+func (properties *GradleProperties) Files() (map[string][]byte, error) {
+	var edits []textEdit
+	for key, value := range properties.updates {
+		edit := properties.values[key]
+		edit.text = value
+		edits = append(edits, edit)
+	}
+	return map[string][]byte{properties.path: applyTextEdits(properties.raw, edits)}, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import "testing"
+
+func TestGradle(t *testing.T) {
+	tests := []struct {
+		name        string
+		flavor      string
+		raw         string
+		version     string
+		buildNumber string
+		want        string
+	}{
+		{
+			name: "groovy",
+			raw: `android {
+    defaultConfig {
+        applicationId "com.example.app"
+        versionCode 42
+        versionName "1.2.0"
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    defaultConfig {
+        applicationId "com.example.app"
+        versionCode 43
+        versionName "1.3.0"
+    }
+}
+`,
+		},
+		{
+			name: "kotlin",
+			raw: `android {
+    defaultConfig {
+        applicationId = "com.example.app"
+        versionCode = 42
+        versionName = "1.2.0"
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    defaultConfig {
+        applicationId = "com.example.app"
+        versionCode = 43
+        versionName = "1.3.0"
+    }
+}
+`,
+		},
+		{
+			name: "method call and single quotes",
+			raw: `android {
+    defaultConfig {
+        versionCode(42)
+        versionName '1.2.0'
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    defaultConfig {
+        versionCode(43)
+        versionName '1.3.0'
+    }
+}
+`,
+		},
+		{
+			name: "comments and nested blocks",
+			raw: `android {
+    // versionName "0.0.1"
+    defaultConfig {
+        /* versionCode 1 */
+        versionCode 42
+        versionName "1.2.0"
+        ndk {
+            versionName "9.9.9"
+        }
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    // versionName "0.0.1"
+    defaultConfig {
+        /* versionCode 1 */
+        versionCode 43
+        versionName "1.3.0"
+        ndk {
+            versionName "9.9.9"
+        }
+    }
+}
+`,
+		},
+		{
+			name:   "groovy flavor",
+			flavor: "production",
+			raw: `android {
+    defaultConfig {
+        versionCode 1
+        versionName "0.1.0"
+    }
+    productFlavors {
+        staging {
+            versionCode 7
+            versionName "0.7.0"
+        }
+        production {
+            versionCode 42
+            versionName "1.2.0"
+        }
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    defaultConfig {
+        versionCode 1
+        versionName "0.1.0"
+    }
+    productFlavors {
+        staging {
+            versionCode 7
+            versionName "0.7.0"
+        }
+        production {
+            versionCode 43
+            versionName "1.3.0"
+        }
+    }
+}
+`,
+		},
+		{
+			name:   "kotlin flavor",
+			flavor: "production",
+			raw: `android {
+    productFlavors {
+        create("staging") {
+            versionCode = 7
+            versionName = "0.7.0"
+        }
+        create("production") {
+            versionCode = 42
+            versionName = "1.2.0"
+        }
+    }
+}
+`,
+			version:     "1.2.0",
+			buildNumber: "42",
+			want: `android {
+    productFlavors {
+        create("staging") {
+            versionCode = 7
+            versionName = "0.7.0"
+        }
+        create("production") {
+            versionCode = 43
+            versionName = "1.3.0"
+        }
+    }
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "app/build.gradle", VersionKey: gradleVersionKey, BuildNumberKey: gradleBuildNumberKey, Flavor: test.flavor}
+			gradle, err := NewGradle(config, []byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gradle.Version() != test.version || gradle.BuildNumber() != test.buildNumber {
+				t.Fatalf("got %s (%s), want %s (%s)", gradle.Version(), gradle.BuildNumber(), test.version, test.buildNumber)
+			}
+
+			gradle.SetVersion("1.3.0", "43")
+			files, err := gradle.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(files[config.Path]); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGradleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		flavor string
+		raw    string
+	}{
+		{name: "missing key", raw: `android { defaultConfig { versionName "1.2.0" } }`},
+		{name: "not a literal", raw: `android { defaultConfig { versionCode 42; versionName "${major}.2.0" } }`},
+		{name: "computed", raw: `android { defaultConfig { versionCode computeCode(); versionName "1.2.0" } }`},
+		{name: "unterminated string", raw: `android { defaultConfig { versionCode 42; versionName "1.2.0 } }`},
+		{name: "unterminated comment", raw: `android { /* defaultConfig { versionCode 42 } }`},
+		{name: "missing flavor", flavor: "production", raw: `android { productFlavors { staging { versionCode 42; versionName "1.2.0" } } }`},
+		{name: "no flavors", flavor: "production", raw: `android { defaultConfig { versionCode 42; versionName "1.2.0" } }`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "app/build.gradle", VersionKey: gradleVersionKey, BuildNumberKey: gradleBuildNumberKey, Flavor: test.flavor}
+			if _, err := NewGradle(config, []byte(test.raw)); err == nil {
+				t.Error("NewGradle succeeded")
+			}
+		})
+	}
+}
+
+func TestGradleBuildNumberMustBeNumber(t *testing.T) {
+	config := VersionFileConfig{Path: "app/build.gradle", VersionKey: gradleVersionKey, BuildNumberKey: gradleBuildNumberKey}
+	gradle, err := NewGradle(config, []byte(`android { defaultConfig { versionCode 42; versionName "1.2.0" } }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gradle.SetVersion("1.3.0", "1.3.0.43")
+	if _, err := gradle.Files(); err == nil {
+		t.Error("a dotted build number was written to an integer versionCode")
+	}
+}
+
+func TestGradleProperties(t *testing.T) {
+	config := VersionFileConfig{Path: "gradle.properties", VersionKey: gradleVersionKey, BuildNumberKey: gradleBuildNumberKey}
+	raw := "# Version\norg.gradle.jvmargs=-Xmx2g -Dfile.encoding=UTF-8\nversionName = 1.2.0\nversionCode: 42\n"
+	want := "# Version\norg.gradle.jvmargs=-Xmx2g -Dfile.encoding=UTF-8\nversionName = 1.3.0\nversionCode: 43\n"
+
+	properties, err := NewGradleProperties(config, []byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if properties.Version() != "1.2.0" || properties.BuildNumber() != "42" {
+		t.Fatalf("got %s (%s), want 1.2.0 (42)", properties.Version(), properties.BuildNumber())
+	}
+	properties.SetVersion("1.3.0", "43")
+	files, err := properties.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files[config.Path]); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/google/go-github/github"
+)
+
+// releaseTagPattern matches release tags such as v1.2.3, 1.2.3+45 and
+// 1.2.3(45).
+var releaseTagPattern = regexp.MustCompile(`^v?([0-9][0-9A-Za-z.\-]*?)(?:\+([0-9.]+)|\(([0-9.]+)\))?$`)
+
+// releaseTitlePattern matches the titles of the release pull requests.
+var releaseTitlePattern = regexp.MustCompile(`^Release (\S+) \((\S+)\)$`)
+
+// tagVersion returns the version of a release tag.
+func tagVersion(name string) (string, bool) {
+	m := releaseTagPattern.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	if _, ok := compareVersions(m[1], m[1]); !ok {
+		return "", false
+	}
+	return m[1], true
+}
+
+func releaseTitle(version, buildNumber string) string {
+	return fmt.Sprintf("Release %s (%s)", version, buildNumber)
+}
+
+// shippedRelease is a version and build number that has been released or is
+// about to be released.
+type shippedRelease struct {
+	version     string
+	buildNumber string
+	// source describes where the release is found, e.g. "tag v1.2.3".
+	source string
+}
+
+// findShippedRegression returns a release of the app that the version and
+// build number of a release from the branch do not follow, or nil if there is
+// none. The tags are checked first; the open or merged release pull requests
+// into the branch are only paged through until a regression is found.
+func findShippedRegression(app *App, branch, version, buildNumber string) (*shippedRelease, error) {
+	tags, err := app.Service.Tags()
+	if err != nil {
+		return nil, err
+	}
+	var releases []shippedRelease
+	for _, tag := range tags {
+		if release, ok := tagRelease(tag.GetName()); ok {
+			releases = append(releases, release)
+		}
+	}
+	if regression := findRegression(version, buildNumber, releases); regression != nil {
+		return regression, nil
+	}
+
+	var regression *shippedRelease
+	err = app.Service.PullRequests("all", branch, func(pullRequests []*github.PullRequest) bool {
+		regression = findRegression(version, buildNumber, app.pullRequestReleases(pullRequests))
+		return regression == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return regression, nil
+}
+
+// pullRequestReleases returns the releases of the open or merged release pull
+// requests of the app.
+func (app *App) pullRequestReleases(pullRequests []*github.PullRequest) []shippedRelease {
+	var releases []shippedRelease
+	for _, pr := range pullRequests {
+		if pr.GetState() != "open" && pr.MergedAt == nil {
+			continue
+		}
+		if !app.isReleaseBranch(pr.GetHead().GetRef()) {
+			continue
+		}
+		if m := releaseTitlePattern.FindStringSubmatch(pr.GetTitle()); m != nil {
+			releases = append(releases, shippedRelease{
+				version:     m[1],
+				buildNumber: m[2],
+				source:      fmt.Sprintf("pull request %s", pr.GetHTMLURL()),
+			})
+		}
+	}
+	return releases
+}
+
+// tagRelease returns the release of a release tag.
+func tagRelease(name string) (shippedRelease, bool) {
+	m := releaseTagPattern.FindStringSubmatch(name)
+	if m == nil {
+		return shippedRelease{}, false
+	}
+	return shippedRelease{
+		version:     m[1],
+		buildNumber: m[2] + m[3],
+		source:      fmt.Sprintf("tag %s", name),
+	}, true
+}
+
+// isReleaseBranch reports whether the branch is a release branch pushed to one
+// of the destinations of the app.
+func (app *App) isReleaseBranch(branch string) bool {
+	for _, destination := range app.Destinations {
+		if strings.HasPrefix(branch, destination.BranchPrefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// findRegression returns the highest release that the version and build
+// number do not follow, or nil if they are higher than all releases. A
+// release with the same version but no build number does not block a new
+// build of the version.
+func findRegression(version, buildNumber string, releases []shippedRelease) *shippedRelease {
+	var regression *shippedRelease
+	for i, release := range releases {
+		c, ok := compareVersions(version, release.version)
+		if !ok || c > 0 {
+			continue
+		}
+		if c == 0 {
+			b, ok := compareBuildNumbers(buildNumber, release.buildNumber)
+			if !ok || b > 0 {
+				continue
+			}
+		}
+		if regression == nil || higherRelease(release, *regression) {
+			regression = &releases[i]
+		}
+	}
+	return regression
+}
+
+func higherRelease(a, b shippedRelease) bool {
+	c, _ := compareVersions(a.version, b.version)
+	if c != 0 {
+		return c > 0
+	}
+	c, _ = compareBuildNumbers(a.buildNumber, b.buildNumber)
+	return c > 0
+}
+
+// compareVersions compares two versions like strings.Compare. Apple style
+// versions are compared by their components; other versions are compared as
+// semantic versions. It returns false if the versions can not be compared.
+func compareVersions(a, b string) (int, bool) {
+	if va, ok := parseAppleVersion(a, 0); ok {
+		if vb, ok := parseAppleVersion(b, 0); ok {
+			return va.compare(vb), true
+		}
+	}
+	va, err := semver.ParseTolerant(a)
+	if err != nil {
+		return 0, false
+	}
+	vb, err := semver.ParseTolerant(b)
+	if err != nil {
+		return 0, false
+	}
+	return va.Compare(vb), true
+}
+
+func compareBuildNumbers(a, b string) (int, bool) {
+	va, ok := parseAppleVersion(a, 0)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := parseAppleVersion(b, 0)
+	if !ok {
+		return 0, false
+	}
+	return va.compare(vb), true
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestTagRelease(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		buildNumber string
+		ok          bool
+	}{
+		{name: "v1.2.3", version: "1.2.3", ok: true},
+		{name: "1.2.3+45", version: "1.2.3", buildNumber: "45", ok: true},
+		{name: "1.2.3(45)", version: "1.2.3", buildNumber: "45", ok: true},
+		{name: "v2.0.0-beta.1+7", version: "2.0.0-beta.1", buildNumber: "7", ok: true},
+		{name: "1.2(1.2.45)", version: "1.2", buildNumber: "1.2.45", ok: true},
+		{name: "release-1.2.3"},
+		{name: "latest"},
+	}
+
+	for _, test := range tests {
+		release, ok := tagRelease(test.name)
+		if ok != test.ok || release.version != test.version || release.buildNumber != test.buildNumber {
+			t.Errorf("tagRelease(%q) = %q (%q), %v, want %q (%q), %v", test.name, release.version, release.buildNumber, ok, test.version, test.buildNumber, test.ok)
+		}
+	}
+}
+
+func TestFindRegression(t *testing.T) {
+	tagReleases := func(names ...string) []shippedRelease {
+		var releases []shippedRelease
+		for _, name := range names {
+			if release, ok := tagRelease(name); ok {
+				releases = append(releases, release)
+			}
+		}
+		return releases
+	}
+	releases := tagReleases("v1.1.0", "1.2.0+40", "1.2.0(42)", "v1.3.0-beta.2", "latest")
+	builds := tagReleases("v1.1.0", "1.2.0+40", "1.2.0(42)")
+	pullRequest := shippedRelease{version: "1.2.1", buildNumber: "50", source: "pull request"}
+
+	tests := []struct {
+		releases    []shippedRelease
+		version     string
+		buildNumber string
+		want        string
+	}{
+		{releases: releases, version: "1.3.0", buildNumber: "43"},
+		{releases: releases, version: "1.3.0-beta.3", buildNumber: "43"},
+		{releases: builds, version: "1.2.1", buildNumber: "43"},
+		// The highest release the version does not follow is reported.
+		{releases: releases, version: "1.0.0", buildNumber: "99", want: "tag v1.3.0-beta.2"},
+		{releases: releases, version: "1.3.0-beta.1", buildNumber: "99", want: "tag v1.3.0-beta.2"},
+		{releases: releases, version: "1.3.0-alpha.5", buildNumber: "99", want: "tag v1.3.0-beta.2"},
+		// A new build of a released version must have a higher build number.
+		{releases: builds, version: "1.2.0", buildNumber: "43"},
+		{releases: builds, version: "1.2.0", buildNumber: "42", want: "tag 1.2.0(42)"},
+		{releases: builds, version: "1.2.0", buildNumber: "41", want: "tag 1.2.0(42)"},
+		// A tag without a build number does not block a new build.
+		{releases: releases, version: "1.3.0-beta.2", buildNumber: "1"},
+		// Apple style versions are compared by their components.
+		{releases: builds, version: "1.2", buildNumber: "42", want: "tag 1.2.0(42)"},
+		{releases: builds, version: "1.2.0.1", buildNumber: "1"},
+		// Open and merged release pull requests count as releases.
+		{releases: append(builds, pullRequest), version: "1.2.1", buildNumber: "51"},
+		{releases: append(builds, pullRequest), version: "1.2.1", buildNumber: "43", want: "pull request"},
+		{releases: append(releases, pullRequest), version: "1.2.1", buildNumber: "43", want: "tag v1.3.0-beta.2"},
+	}
+
+	for _, test := range tests {
+		var got string
+		if regression := findRegression(test.version, test.buildNumber, test.releases); regression != nil {
+			got = regression.source
+		}
+		if got != test.want {
+			t.Errorf("findRegression(%q, %q) = %q, want %q", test.version, test.buildNumber, got, test.want)
+		}
+	}
+}
+
+func TestFindShippedRegression(t *testing.T) {
+	pages := []string{
+		`[{"number": 3, "state": "closed", "title": "Release 1.2.1 (44)", "head": {"ref": "_release/1.2.1"}, "html_url": "3"},
+		  {"number": 2, "state": "open", "title": "Release 9.9.9 (99)", "head": {"ref": "feature"}, "html_url": "2"}]`,
+		`[{"number": 1, "state": "closed", "merged_at": "2018-06-13T00:00:00Z", "title": "Release 1.2.1 (43)", "head": {"ref": "_release/1.2.1"}, "html_url": "1"}]`,
+		`[{"number": 0, "state": "open", "title": "Release 1.2.1 (50)", "head": {"ref": "_release/1.2.1"}, "html_url": "0"}]`,
+	}
+
+	tests := []struct {
+		name        string
+		version     string
+		buildNumber string
+		want        string
+		requests    int
+	}{
+		// A regression in the tags does not need the pull requests.
+		{name: "tag", version: "1.1.0", buildNumber: "99", want: "tag 1.2.0(42)"},
+		// Paging stops at the first page with a regression.
+		{name: "merged pull request", version: "1.2.1", buildNumber: "43", want: "pull request 1", requests: 2},
+		{name: "open pull request", version: "1.2.1", buildNumber: "45", want: "pull request 0", requests: 3},
+		{name: "none", version: "1.2.1", buildNumber: "51", requests: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			service := testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/repository/tags":
+					w.Write([]byte(`[{"name": "v1.1.0"}, {"name": "1.2.0(42)"}]`))
+				case "/repos/owner/repository/pulls":
+					if base := r.URL.Query().Get("base"); base != "main" {
+						t.Errorf("base = %q, want %q", base, "main")
+					}
+					page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+					if page == 0 {
+						page = 1
+					}
+					if page < len(pages) {
+						w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+					}
+					requests++
+					w.Write([]byte(pages[page-1]))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			app := &App{Service: service, Destinations: defaultDestinations}
+
+			regression, err := findShippedRegression(app, "main", test.version, test.buildNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if regression != nil {
+				got = regression.source
+			}
+			if got != test.want {
+				t.Errorf("findShippedRegression() = %q, want %q", got, test.want)
+			}
+			if requests != test.requests {
+				t.Errorf("%d pull request pages fetched, want %d", requests, test.requests)
+			}
+		})
+	}
+}
@@ -1,103 +1,314 @@
 package main
 
 import (
-	"github.com/blang/semver"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+
 	"howett.net/plist"
-	"strconv"
 )
 
 const (
-	versionKey     = "CFBundleShortVersionString"
-	buildNumberKey = "CFBundleVersion"
+	infoPlistVersionKey     = "CFBundleShortVersionString"
+	infoPlistBuildNumberKey = "CFBundleVersion"
 )
 
+// buildSettingReferencePattern matches values such as $(MARKETING_VERSION),
+// ${MARKETING_VERSION} and $(MARKETING_VERSION:default=1.0).
+var buildSettingReferencePattern = regexp.MustCompile(`^\$[({]([A-Za-z_][A-Za-z0-9_]*)(?::[^)}]*)?[)}]$`)
+
+// InfoPlist is a version file that keeps the version and build number in an
+// Info.plist. Only the edited values are replaced, so the rest of the file
+// and its format (XML, binary or OpenStep) are kept as they are.
 type InfoPlist struct {
-	Path   string
+	path   string
 	object map[string]interface{}
 	raw    []byte
+	format int
+
+	// Info.plist keys, or the build settings they refer to.
+	versionKey     string
+	buildNumberKey string
+	versionSetting string
+	buildSetting   string
+
+	xcconfigPath string
+	xcconfig     *xcconfigFile
+	updates      map[string]string
 }
 
-func NewInfoPlist(bytes []byte) (*InfoPlist, error) {
+func NewInfoPlist(config VersionFileConfig, bytes []byte, fetch fetchFunc) (*InfoPlist, error) {
 	var object map[string]interface{}
-	if _, err := plist.Unmarshal(bytes, &object); err != nil {
+	format, err := plist.Unmarshal(bytes, &object)
+	if err != nil {
 		return nil, err
 	}
 
 	infoPlist := InfoPlist{
-		object: object,
-		raw:    bytes,
+		path:           config.Path,
+		object:         object,
+		raw:            bytes,
+		format:         format,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		xcconfigPath:   config.Xcconfig,
+		updates:        map[string]string{},
+	}
+
+	if infoPlist.versionSetting, err = infoPlist.resolve(infoPlist.versionKey, fetch); err != nil {
+		return nil, err
+	}
+	if infoPlist.buildSetting, err = infoPlist.resolve(infoPlist.buildNumberKey, fetch); err != nil {
+		return nil, err
 	}
 
 	return &infoPlist, nil
 }
 
-func (infoPlist *InfoPlist) VersionString() string {
-	return infoPlist.object[versionKey].(string)
+// resolve returns the build setting the value of key refers to, following
+// references between build settings in the xcconfig file. It returns an empty
+// string if the value is a literal.
+func (infoPlist *InfoPlist) resolve(key string, fetch fetchFunc) (string, error) {
+	value, ok := infoPlist.object[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not found", key)
+	}
+
+	m := buildSettingReferencePattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", nil
+	}
+	if infoPlist.xcconfigPath == "" {
+		return "", fmt.Errorf("%s refers to %s, but no xcconfig is configured to resolve it", key, value)
+	}
+	if infoPlist.xcconfig == nil {
+		bytes, err := fetch(infoPlist.xcconfigPath)
+		if err != nil {
+			return "", err
+		}
+		infoPlist.xcconfig = parseXcconfig(bytes)
+	}
+
+	setting := m[1]
+	for seen := map[string]bool{}; !seen[setting]; {
+		seen[setting] = true
+		value, ok := infoPlist.xcconfig.Value(setting)
+		if !ok {
+			return "", fmt.Errorf("%s refers to $(%s), but it is not defined in %s", key, setting, infoPlist.xcconfigPath)
+		}
+		m := buildSettingReferencePattern.FindStringSubmatch(value)
+		if m == nil {
+			return setting, nil
+		}
+		setting = m[1]
+	}
+	return "", fmt.Errorf("%s has a circular build setting reference in %s", key, infoPlist.xcconfigPath)
 }
 
-func (infoPlist *InfoPlist) BuildNumberString() string {
-	return infoPlist.object[buildNumberKey].(string)
+func (infoPlist *InfoPlist) value(key, setting string) string {
+	if setting != "" {
+		value, _ := infoPlist.xcconfig.Value(setting)
+		return value
+	}
+	if value, ok := infoPlist.updates[key]; ok {
+		return value
+	}
+	return infoPlist.object[key].(string)
 }
 
-func (infoPlist *InfoPlist) NextMajor() (string, error) {
-	versionString := infoPlist.VersionString()
-	version, err := semver.Make(versionString)
-	if err != nil {
-		return versionString, err
+func (infoPlist *InfoPlist) setValue(key, setting, value string) {
+	if setting != "" {
+		infoPlist.xcconfig.SetValue(setting, value)
+		return
 	}
+	infoPlist.updates[key] = value
+}
+
+func (infoPlist *InfoPlist) Version() string {
+	return infoPlist.value(infoPlist.versionKey, infoPlist.versionSetting)
+}
 
-	version.Major += 1
-	version.Minor = 0
-	version.Patch = 0
+func (infoPlist *InfoPlist) BuildNumber() string {
+	return infoPlist.value(infoPlist.buildNumberKey, infoPlist.buildSetting)
+}
 
-	return version.String(), nil
+func (infoPlist *InfoPlist) NextMajor() (string, error) {
+	return nextMajor(infoPlist.Version())
 }
 
 func (infoPlist *InfoPlist) NextMinor() (string, error) {
-	versionString := infoPlist.VersionString()
-	version, err := semver.Make(versionString)
-	if err != nil {
-		return versionString, err
-	}
+	return nextMinor(infoPlist.Version())
+}
 
-	version.Minor += 1
-	version.Patch = 0
+func (infoPlist *InfoPlist) NextPatch() (string, error) {
+	return nextPatch(infoPlist.Version())
+}
 
-	return version.String(), nil
+func (infoPlist *InfoPlist) NextBuildNumber() (string, error) {
+	return nextBuildNumber(infoPlist.BuildNumber())
 }
 
-func (infoPlist *InfoPlist) NextPatch() (string, error) {
-	versionString := infoPlist.VersionString()
-	version, err := semver.Make(versionString)
-	if err != nil {
-		return versionString, err
+func (infoPlist *InfoPlist) SetVersion(version string, build string) {
+	infoPlist.setValue(infoPlist.versionKey, infoPlist.versionSetting, version)
+	infoPlist.setValue(infoPlist.buildNumberKey, infoPlist.buildSetting, build)
+}
+
+func (infoPlist *InfoPlist) Files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	if len(infoPlist.updates) > 0 {
+		bytes, err := infoPlist.edited()
+		if err != nil {
+			return nil, err
+		}
+		files[infoPlist.path] = bytes
 	}
 
-	version.Patch += 1
+	if infoPlist.xcconfig != nil && infoPlist.xcconfig.Changed() {
+		bytes, err := infoPlist.xcconfig.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		files[infoPlist.xcconfigPath] = bytes
+	}
 
-	return version.String(), nil
+	return files, nil
 }
 
-func (infoPlist *InfoPlist) NextBuildNumber() (string, error) {
-	buildNumberString := infoPlist.BuildNumberString()
-	buildNumber, err := strconv.Atoi(buildNumberString)
-	if err != nil {
-		return buildNumberString, err
+// edited returns the raw Info.plist with the updated values replaced.
+func (infoPlist *InfoPlist) edited() ([]byte, error) {
+	switch infoPlist.format {
+	case plist.XMLFormat:
+		return editXMLPlist(infoPlist.raw, infoPlist.updates)
+	case plist.OpenStepFormat, plist.GNUStepFormat:
+		return editOpenStepPlist(infoPlist.raw, infoPlist.updates)
+	default:
+		// Binary property lists have no layout to keep; write them back in
+		// the same format.
+		object := map[string]interface{}{}
+		for key, value := range infoPlist.object {
+			object[key] = value
+		}
+		for key, value := range infoPlist.updates {
+			object[key] = value
+		}
+		return plist.Marshal(object, infoPlist.format)
 	}
+}
+
+// editXMLPlist replaces the <string> values of the keys in the top-level
+// dictionary of an XML property list.
+func editXMLPlist(raw []byte, updates map[string]string) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+
+	var edits []textEdit
+	var stack []string
+	var key string
+	found := map[string]bool{}
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			topLevel := len(stack) == 2 && stack[1] == "dict"
+			stack = append(stack, t.Name.Local)
+			if !topLevel {
+				continue
+			}
+
+			if t.Name.Local == "key" {
+				text, _, _, err := xmlElementText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				stack = stack[:len(stack)-1]
+				key = text
+				continue
+			}
+
+			name := key
+			key = ""
+			value, ok := updates[name]
+			if !ok {
+				continue
+			}
+			if t.Name.Local != "string" {
+				return nil, fmt.Errorf("value of %s is not a string", name)
+			}
+
+			start := int(decoder.InputOffset())
+			_, end, _, err := xmlElementText(decoder)
+			if err != nil {
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
 
-	return strconv.Itoa(buildNumber + 1), nil
+			var escaped bytes.Buffer
+			xml.EscapeText(&escaped, []byte(value))
+			if bytes.HasSuffix(raw[offset:start], []byte("/>")) {
+				edits = append(edits, textEdit{start: offset, end: start, text: "<string>" + escaped.String() + "</string>"})
+			} else {
+				edits = append(edits, textEdit{start: start, end: end, text: escaped.String()})
+			}
+			found[name] = true
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for key := range updates {
+		if !found[key] {
+			return nil, fmt.Errorf("%s is not found", key)
+		}
+	}
+	return applyTextEdits(raw, edits), nil
 }
 
-func (infoPlist *InfoPlist) SetVersion(version string, build string) {
-	infoPlist.object[versionKey] = version
-	infoPlist.object[buildNumberKey] = build
+// xmlElementText reads the rest of the current element and returns its text
+// and the offsets where its end tag starts and ends.
+func xmlElementText(decoder *xml.Decoder) (string, int, int, error) {
+	var text bytes.Buffer
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return "", 0, 0, err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			return text.String(), offset, int(decoder.InputOffset()), nil
+		case xml.StartElement:
+			return "", 0, 0, fmt.Errorf("unexpected element <%s>", t.Name.Local)
+		}
+	}
 }
 
-func (infoPlist *InfoPlist) serialized() ([]byte, error) {
-	bytes, err := plist.MarshalIndent(&infoPlist.object, plist.XMLFormat, "\t")
+// editOpenStepPlist replaces the values of the keys in the top-level
+// dictionary of an OpenStep property list.
+func editOpenStepPlist(raw []byte, updates map[string]string) ([]byte, error) {
+	root, err := parseOpenStep(raw)
 	if err != nil {
 		return nil, err
 	}
 
-	return bytes, nil
+	var edits []textEdit
+	for key, value := range updates {
+		v := root.Get(key)
+		if v == nil {
+			return nil, fmt.Errorf("%s is not found", key)
+		}
+		edits = append(edits, textEdit{start: v.start, end: v.end, text: quoteOpenStep(value, v.quoted(raw))})
+	}
+	return applyTextEdits(raw, edits), nil
 }
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"howett.net/plist"
+)
+
+func loadInfoPlist(t *testing.T, raw []byte) *InfoPlist {
+	config := VersionFileConfig{Path: "Info.plist", VersionKey: infoPlistVersionKey, BuildNumberKey: infoPlistBuildNumberKey}
+	infoPlist, err := NewInfoPlist(config, raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infoPlist.Version() != "1.2.0" || infoPlist.BuildNumber() != "42" {
+		t.Fatalf("got %s (%s), want 1.2.0 (42)", infoPlist.Version(), infoPlist.BuildNumber())
+	}
+	infoPlist.SetVersion("1.3.0", "43")
+	return infoPlist
+}
+
+// changedLines returns the lines of b that differ from a. Edits never add or
+// remove lines, so the lines are compared one by one.
+func changedLines(t *testing.T, a, b []byte) []string {
+	linesA := bytes.Split(a, []byte("\n"))
+	linesB := bytes.Split(b, []byte("\n"))
+	if len(linesA) != len(linesB) {
+		t.Fatalf("number of lines changed from %d to %d", len(linesA), len(linesB))
+	}
+	var changed []string
+	for i := range linesA {
+		if !bytes.Equal(linesA[i], linesB[i]) {
+			changed = append(changed, string(bytes.TrimSpace(linesB[i])))
+		}
+	}
+	return changed
+}
+
+func TestInfoPlistGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  int
+		changed []string
+	}{
+		{
+			name:    "xml",
+			format:  plist.XMLFormat,
+			changed: []string{"<string>1.3.0</string>", "<string>43</string>"},
+		},
+		{
+			name:    "openstep",
+			format:  plist.OpenStepFormat,
+			changed: []string{`CFBundleShortVersionString = "1.3.0";`, "CFBundleVersion = 43;"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := ioutil.ReadFile(filepath.Join("testdata", "infoplist", test.name+".plist"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", "infoplist", test.name+".golden.plist"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			infoPlist := loadInfoPlist(t, raw)
+			if infoPlist.format != test.format {
+				t.Fatalf("got format %d, want %d", infoPlist.format, test.format)
+			}
+			files, err := infoPlist.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := files["Info.plist"]
+			if !bytes.Equal(got, golden) {
+				t.Errorf("got\n%s\nwant\n%s", got, golden)
+			}
+			if changed := changedLines(t, raw, got); !reflect.DeepEqual(changed, test.changed) {
+				t.Errorf("changed lines %q, want %q", changed, test.changed)
+			}
+		})
+	}
+}
+
+func TestInfoPlistBinary(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "infoplist", "binary.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadInfoPlist(t, raw).Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want, got map[string]interface{}
+	if _, err := plist.Unmarshal(raw, &want); err != nil {
+		t.Fatal(err)
+	}
+	want[infoPlistVersionKey] = "1.3.0"
+	want[infoPlistBuildNumberKey] = "43"
+
+	format, err := plist.Unmarshal(files["Info.plist"], &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != plist.BinaryFormat {
+		t.Errorf("got format %d, want binary", format)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEditXMLPlist(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		value string
+		want  string
+	}{
+		{
+			name:  "empty string",
+			raw:   "<plist><dict><key>CFBundleVersion</key><string/></dict></plist>",
+			value: "43",
+			want:  "<plist><dict><key>CFBundleVersion</key><string>43</string></dict></plist>",
+		},
+		{
+			name:  "escaped value",
+			raw:   "<plist><dict><key>CFBundleVersion</key><string>42</string></dict></plist>",
+			value: "43&b",
+			want:  "<plist><dict><key>CFBundleVersion</key><string>43&amp;b</string></dict></plist>",
+		},
+		{
+			name:  "nested dict is left alone",
+			raw:   "<plist><dict><key>A</key><dict><key>CFBundleVersion</key><string>42</string></dict><key>CFBundleVersion</key><string>42</string></dict></plist>",
+			value: "43",
+			want:  "<plist><dict><key>A</key><dict><key>CFBundleVersion</key><string>42</string></dict><key>CFBundleVersion</key><string>43</string></dict></plist>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := editXMLPlist([]byte(test.raw), map[string]string{infoPlistBuildNumberKey: test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+
+	if _, err := editXMLPlist([]byte("<plist><dict><key>A</key><dict><key>CFBundleVersion</key><string>42</string></dict></dict></plist>"), map[string]string{infoPlistBuildNumberKey: "43"}); err == nil {
+		t.Error("a key only in a nested dict was edited")
+	}
+}
@@ -1,22 +1,28 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"github.com/blang/semver"
 	"github.com/nlopes/slack"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type interactionHandler struct {
-	slackClient       *slack.Client
-	verificationToken string
+	slackClient *slack.Client
+	api         slackAPI
+	sessions    sessionStore
+	deliveries  *deliveries
+	verifier    slackVerifier
+	apps        Apps
+	// now returns the current time. It is replaced to fix the date versions
+	// and build numbers are computed from.
+	now func() time.Time
 }
 
 func (h interactionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -33,417 +39,588 @@ func (h interactionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonStr, err := url.QueryUnescape(string(buf)[8:])
+	if err := h.verifier.verifySignature(r.Header, buf); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(buf))
 	if err != nil {
-		sugar.Errorf("Failed to un-escape request body: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		sugar.Errorf("Failed to parse request body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	var message slack.AttachmentActionCallback
-	if err := json.Unmarshal([]byte(jsonStr), &message); err != nil {
-		sugar.Errorf("Failed to decode json message from slack: %s", jsonStr)
+	var payload interactionPayload
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		sugar.Errorf("Failed to decode json message from slack: %s", form.Get("payload"))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if message.Token != h.verificationToken {
-		sugar.Errorf("Invalid token: %s", message.Token)
+	if err := h.verifier.verifyToken(payload.Token); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	action := message.Actions[0]
-	parameters := NewBuildParameters(action.Value)
-	if action.Value == "" {
-		parameters = NewBuildParameters(action.SelectedOptions[0].Value)
-	}
-	switch action.Name {
-	case actionBranch:
-		// FIXME
-		var currentVersion string
-		var currentBuildNumber string
-		var nextPatch string
-		var nextMinor string
-		var nextMajor string
-		var nextBuildNumber string
-		var tempFile *os.File
-
-		file, err := service.File(parameters.Branch, service.InfoPlistPath)
-		if err != nil {
-			responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
+	// Slack shows an error if an interaction is not acknowledged within 3
+	// seconds, so the work is done after the response and the result is
+	// shown through the response_url or the API.
+	switch payload.Type {
+	case "block_actions":
+		if len(payload.Actions) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		w.WriteHeader(http.StatusOK)
+		key := payload.actionKey()
+		if !h.deliveries.first(key, h.now()) {
+			sugar.Infof("Ignoring a duplicate action: %s", payload.Actions[0].ActionID)
+			return
+		}
+		go func() {
+			// A failed action is forgotten, so that it can be clicked again.
+			if err := h.handleAction(payload); err != nil {
+				h.deliveries.forget(key)
+			}
+		}()
+	case "view_submission":
+		key := fmt.Sprintf("view/%s/%s", payload.View.ID, payload.View.CallbackID)
+		if !h.deliveries.first(key, h.now()) {
+			sugar.Infof("Ignoring a duplicate submission: %s", payload.View.CallbackID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// A submission rejected with errors is forgotten, so that the
+		// corrected form can be submitted.
+		if err := h.submitView(w, payload); err != nil {
+			h.deliveries.forget(key)
+		}
+	case "shortcut", "message_action":
+		w.WriteHeader(http.StatusOK)
+		go h.openShortcut(payload)
+	default:
+		sugar.Errorf("Unsupported interaction: %s", payload.Type)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// handleAction handles the action. An error is returned if it failed and may
+// be retried; it is already shown to the user.
+func (h interactionHandler) handleAction(payload interactionPayload) error {
+	action := payload.Actions[0]
+	name := actionName(action.ActionID)
+	value := action.Value
+	if action.Type == "static_select" {
+		value = action.SelectedOption.Value
+	}
 
-		tempFile, err = ioutil.TempFile("", "applebot-")
+	// The state of the release is kept in a session. Its ID is the value of
+	// the cancel button next to the action.
+	sessionID := payload.Message.sessionID(action.BlockID)
+	if name == actionCancel {
+		if err := h.sessions.delete(sessionID); err != nil {
+			sugar.Errorf("Failed to delete session: %s", err)
+		}
+		respondMessage(payload.ResponseURL, fmt.Sprintf("Operation canceled by <@%s>.", payload.User.ID), "")
+		return nil
+	}
+	session, err := h.sessions.get(sessionID)
+	if err != nil {
+		return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s", err))
+	}
+	if session == nil {
+		return respondFailure(payload.ResponseURL, "This release has expired.", "Mention the bot to start again.")
+	}
+	parameters := session.Parameters.with(name, value)
+
+	app := h.apps.Find(parameters.App)
+	if app == nil {
+		return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("Unknown app: %s", parameters.App))
+	}
+
+	switch name {
+	case actionApp, actionBranch, actionVersion, actionBuildNumber:
+		text, actions, err := h.advance(app, sessionID, session, name, parameters)
 		if err != nil {
-			responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-			return
+			respondWizardError(payload.ResponseURL, err)
+			return err
+		}
+		respondAction(payload.ResponseURL, text, actions)
+	case actionForm:
+		h.openReleaseForm(payload.TriggerID, app, payload.Channel.ID)
+	case actionDestination, actionOverride:
+		destination := app.Destination(parameters.Destination)
+		if destination == nil {
+			return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("Unknown destination: %s", parameters.Destination))
+		}
+		if !destination.Accepts(parameters.Version) {
+			return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s does not accept %s versions: %s", destination.Description, versionChannel(parameters.Version), parameters.Version))
 		}
 
-		if strings.HasSuffix(service.InfoPlistPath, ".xcconfig") {
-			versions := map[string]string{}
+		versionFile, err := LoadVersionFiles(app.VersionFiles, session.Files.fetch)
+		if err != nil {
+			return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s", err))
+		}
+		if mismatches := versionFile.Mismatches(); len(mismatches) > 0 {
+			return respondFailure(payload.ResponseURL, "Version files do not match.", strings.Join(mismatches, "\n"))
+		}
 
-			lines := strings.Split(string(file), "\n")
-			for _, line := range lines {
-				if !strings.Contains(line, "=") {
-					continue
+		nextVersion := fmt.Sprintf("%s (%s)", parameters.Version, parameters.BuildNumber)
+		if !parameters.Override {
+			release, err := findShippedRegression(app, parameters.Branch, parameters.Version, parameters.BuildNumber)
+			if err != nil {
+				return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s", err))
+			}
+			if release != nil {
+				shipped := fmt.Sprintf("%s (%s)", release.version, release.buildNumber)
+				if release.buildNumber == "" {
+					shipped = release.version
+				}
+				session.Parameters = parameters
+				if err := h.sessions.put(sessionID, session); err != nil {
+					return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s", err))
 				}
-				pair := strings.Split(line, "=")
-				versions[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+				respondAction(payload.ResponseURL, fmt.Sprintf("App: `%s` ✔︎\nBranch: `%s` ✔︎\nNext Version: `%s`\n:warning: `%s` is not higher than `%s` of %s. The store will reject the build.", app.Name, parameters.Branch, nextVersion, nextVersion, shipped, release.source), overrideOptions(parameters, sessionID))
+				return nil
 			}
+		}
 
-			currentVersion = versions["APP_VERSION"]
-			currentBuildNumber = versions["BUILD_VERSION"]
+		// The release is started once, by the action that takes the
+		// session. Buttons of other destinations may be clicked at the same
+		// time.
+		claimed, err := h.sessions.take(sessionID)
+		if err != nil {
+			return respondFailure(payload.ResponseURL, "Error occurred.", fmt.Sprintf("%s", err))
+		}
+		if claimed == nil {
+			sugar.Infof("Ignoring a release of session %s that is started already", sessionID)
+			return nil
+		}
+		respondMessage(payload.ResponseURL, fmt.Sprintf("Releasing %s `%s` to %s ...", app.Name, nextVersion, destination.Description), "")
 
-			version, err := semver.Make(currentVersion)
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			version.Patch += 1
-			nextPatch = version.String()
+		h.release(payload.Channel.ID, app, destination, parameters, versionFile)
+	default:
+		sugar.Errorf("Unknown action: %s", action.ActionID)
+	}
+	return nil
+}
 
-			version.Minor += 1
-			version.Patch = 0
-			nextMinor = version.String()
+// wizardError is an error of a step of the release wizard. The title is shown
+// above the error.
+type wizardError struct {
+	title string
+	err   error
+}
 
-			version.Major += 1
-			version.Minor = 0
-			version.Patch = 0
-			nextMajor = version.String()
+func (e wizardError) Error() string {
+	return fmt.Sprintf("%s %s", e.title, e.err)
+}
 
-			buildNumber, err := strconv.Atoi(currentBuildNumber)
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			nextBuildNumber = strconv.Itoa(buildNumber + 1)
-		} else {
-			infoPlist, err := NewInfoPlist(file)
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
+// respondFailure shows the error and returns it.
+func respondFailure(responseURL string, title string, text string) error {
+	respondError(responseURL, title, text)
+	return fmt.Errorf("%s %s", title, text)
+}
 
-			bytes, err := infoPlist.serialized()
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			tempFile.Write(bytes)
+func respondWizardError(responseURL string, err error) {
+	if e, ok := err.(wizardError); ok {
+		respondError(responseURL, e.title, fmt.Sprintf("%s", e.err))
+		return
+	}
+	respondError(responseURL, "Error occurred.", fmt.Sprintf("%s", err))
+}
 
-			currentVersion = infoPlist.VersionString()
-			currentBuildNumber = infoPlist.BuildNumberString()
+// advance saves the choice of a step of the release wizard in the session and
+// returns the message of the next step. The choice may also be typed by hand
+// in the slash command, so it is checked again.
+func (h interactionHandler) advance(app *App, sessionID string, session *session, name string, parameters BuildParameters) (string, []blockElement, error) {
+	switch name {
+	case actionApp:
+		session.Parameters = parameters
+		if err := h.sessions.put(sessionID, session); err != nil {
+			return "", nil, err
+		}
+		actions, err := branchOptions(app, sessionID)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("App: `%s` ✔︎\nBranch:", app.Name), actions, nil
+	case actionBranch:
+		snapshot := fileSnapshot{}
+		versionFile, err := LoadVersionFiles(app.VersionFiles, snapshot.record(func(path string) ([]byte, error) {
+			return app.Service.File(parameters.Branch, path)
+		}))
+		if err != nil {
+			return "", nil, err
+		}
+		if mismatches := versionFile.Mismatches(); len(mismatches) > 0 {
+			return "", nil, wizardError{"Version files do not match.", fmt.Errorf("%s", strings.Join(mismatches, "\n"))}
+		}
+		currentVersion := versionFile.Version()
+		currentBuildNumber := versionFile.BuildNumber()
+
+		scheme := versioningSchemes[app.VersioningScheme]
+		nextVersions, err := scheme.next(versionRequest{
+			file:     versionFile,
+			channels: app.Channels(),
+			format:   app.CalVerFormat,
+			now:      h.now(),
+		})
+		if err != nil {
+			return "", nil, wizardError{"Version can not be parsed.", err}
+		}
+		recommendedVersion, recommendation := recommendVersion(app, scheme, versionFile, parameters.Branch, nextVersions)
 
-			nextPatch, err = infoPlist.NextPatch()
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			nextMinor, err = infoPlist.NextMinor()
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			nextMajor, err = infoPlist.NextMajor()
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
-			nextBuildNumber, err = infoPlist.NextBuildNumber()
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
+		nextBuildNumber, err := versionFile.NextBuildNumber()
+		if err != nil {
+			return "", nil, wizardError{"Build number can not be parsed.", err}
 		}
 
 		buildParameters := BuildParameters{
+			App:                app.Name,
 			Branch:             parameters.Branch,
 			Version:            "",
 			BuildNumber:        "",
 			CurrentVersion:     currentVersion,
 			CurrentBuildNumber: currentBuildNumber,
-			NextPatch:          nextPatch,
-			NextMinor:          nextMinor,
-			NextMajor:          nextMajor,
+			NextVersions:       nextVersions,
+			RecommendedVersion: recommendedVersion,
 			NextBuildNumber:    nextBuildNumber,
-			InfoPlist:          tempFile.Name(),
 		}
 
-		responseAction(w, message.OriginalMessage, fmt.Sprintf("Branch: `%s` ✔︎\nCurrent Version: `%s (%s)`\nNext Version:", parameters.Branch, currentVersion, currentBuildNumber), versionOptions(buildParameters))
+		// The version files are loaded again from the snapshot on release, so
+		// that the release is made from the version that was shown.
+		session.Parameters = buildParameters
+		session.Files = snapshot
+		if err := h.sessions.put(sessionID, session); err != nil {
+			return "", nil, err
+		}
+
+		return fmt.Sprintf("App: `%s` ✔︎\nBranch: `%s` ✔︎\nCurrent Version: `%s (%s)`\nNext Version: %s", app.Name, parameters.Branch, currentVersion, currentBuildNumber, recommendation), versionOptions(buildParameters, sessionID), nil
 	case actionVersion:
+		if err := versioningSchemes[app.VersioningScheme].validate(parameters.Version, app.CalVerFormat); err != nil {
+			return "", nil, err
+		}
+		if len(app.DestinationsFor(parameters.Version)) == 0 {
+			return "", nil, fmt.Errorf("no destination accepts %s versions: %s", versionChannel(parameters.Version), parameters.Version)
+		}
+		strategy := buildNumberStrategies[app.BuildNumberStrategy]
+		buildNumber, err := strategy.next(buildNumberRequest{
+			app:                app,
+			branch:             parameters.Branch,
+			currentVersion:     parameters.CurrentVersion,
+			currentBuildNumber: parameters.CurrentBuildNumber,
+			nextBuildNumber:    parameters.NextBuildNumber,
+			version:            parameters.Version,
+			now:                h.now(),
+		})
+		if err != nil {
+			return "", nil, wizardError{"Build number can not be picked.", fmt.Errorf("%s: %s", strategy.description, err)}
+		}
+		// From here on, the next build number is the one the strategy picked.
+		parameters.NextBuildNumber = buildNumber
+
+		session.Parameters = parameters
+		if err := h.sessions.put(sessionID, session); err != nil {
+			return "", nil, err
+		}
+
 		currentVersion := fmt.Sprintf("%s (%s)", parameters.CurrentVersion, parameters.CurrentBuildNumber)
-		responseAction(w, message.OriginalMessage, fmt.Sprintf("Branch: `%s` ✔︎\nCurrent Version: `%s`\nNext Version: `%s` ✔︎\nBuild:", parameters.Branch, currentVersion, parameters.Version), buildNumberOptions(parameters))
+		return fmt.Sprintf("App: `%s` ✔︎\nBranch: `%s` ✔︎\nCurrent Version: `%s`\nNext Version: `%s` ✔︎\nBuild: `%s` is picked by %s", app.Name, parameters.Branch, currentVersion, parameters.Version, buildNumber, strategy.description), buildNumberOptions(parameters, sessionID), nil
 	case actionBuildNumber:
+		if _, err := nextBuildNumber(parameters.BuildNumber); err != nil {
+			return "", nil, err
+		}
+		session.Parameters = parameters
+		if err := h.sessions.put(sessionID, session); err != nil {
+			return "", nil, err
+		}
 		currentVersion := fmt.Sprintf("%s (%s)", parameters.CurrentVersion, parameters.CurrentBuildNumber)
 		nextVersion := fmt.Sprintf("%s (%s)", parameters.Version, parameters.BuildNumber)
-		responseAction(w, message.OriginalMessage, fmt.Sprintf("Branch: `%s` ✔︎\nCurrent Version: `%s`\nNext Version: `%s` ✔︎", parameters.Branch, currentVersion, nextVersion), runOptions(parameters))
-	case actionRelease, actionExternal, actionInternal:
-		// FIXME
+		return fmt.Sprintf("App: `%s` ✔︎\nBranch: `%s` ✔︎\nCurrent Version: `%s`\nNext Version: `%s` ✔︎", app.Name, parameters.Branch, currentVersion, nextVersion), runOptions(app, parameters, sessionID), nil
+	default:
+		return "", nil, fmt.Errorf("unknown action: %s", name)
+	}
+}
 
-		bytes, err := ioutil.ReadFile(parameters.InfoPlist)
-		if err != nil {
-			responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-			return
+// release opens the release pull request and reports the result to the
+// channel.
+func (h interactionHandler) release(channel string, app *App, destination *Destination, parameters BuildParameters, versionFile *VersionFileSet) {
+	versionFile.SetVersion(parameters.Version, parameters.BuildNumber)
+	files, err := versionFile.Files()
+	if err != nil {
+		e := fmt.Errorf("failed to update version files: %s", err)
+		sugar.Error(e)
+		h.slackClient.PostMessage(channel, fmt.Sprintf("%s", e), slack.PostMessageParameters{})
+		return
+	}
+
+	timestamp := strconv.FormatInt(h.now().Unix(), 10)
+	commitBranch := fmt.Sprintf("%s/%s-%s-%s", destination.BranchPrefix, parameters.Version, parameters.BuildNumber, timestamp)
+	title := releaseTitle(parameters.Version, parameters.BuildNumber)
+
+	description := parameters.Notes
+	changelog, err := buildChangelog(app.Service, parameters.Version, parameters.Branch, h.now())
+	if err != nil {
+		sugar.Warnf("Failed to generate the changelog: %s", err)
+		changelog = &Changelog{Version: parameters.Version, Date: h.now()}
+	}
+	changelog.BuildNumber = parameters.BuildNumber
+	changelog.Notes = parameters.Notes
+	if err == nil {
+		if description, err = changelog.Markdown(); err != nil {
+			sugar.Warnf("Failed to render the changelog: %s", err)
 		}
+	}
 
-		var infoPlist *InfoPlist
-		if strings.HasSuffix(service.InfoPlistPath, "Info.plist") {
-			infoPlist, err = NewInfoPlist(bytes)
-			if err != nil {
-				responseError(w, message.OriginalMessage, "Error occurred.", fmt.Sprintf("%s", err))
-				return
-			}
+	notes, err := releaseNoteFiles(app, parameters.Branch, changelog)
+	if err != nil {
+		e := fmt.Errorf("failed to update release notes: %s", err)
+		sugar.Error(e)
+		h.slackClient.PostMessage(channel, fmt.Sprintf("%s", e), slack.PostMessageParameters{})
+		return
+	}
+	for path, content := range notes {
+		if _, ok := files[path]; ok {
+			e := fmt.Errorf("failed to update release notes: %s is also a version file", path)
+			sugar.Error(e)
+			h.slackClient.PostMessage(channel, fmt.Sprintf("%s", e), slack.PostMessageParameters{})
+			return
 		}
+		files[path] = content
+	}
 
-		nextVersion := fmt.Sprintf("%s (%s)", parameters.Version, parameters.BuildNumber)
-		responseMessage(w, message.OriginalMessage, fmt.Sprintf("Releasing `%s` to %s ...", nextVersion, destination(action.Name)), "")
+	u, err := app.Service.PushPullRequest(PullRequest{
+		TargetBranch:  parameters.Branch,
+		CommitBranch:  commitBranch,
+		Files:         files,
+		Title:         title,
+		CommitMessage: description,
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to create pull request %s", err)
+		sugar.Error(e)
+		h.slackClient.PostMessage(channel, fmt.Sprintf("%s", e), slack.PostMessageParameters{})
+	} else {
+		m := fmt.Sprintf("Releasing %s `%s (%s)`", app.Name, parameters.Version, parameters.BuildNumber)
+		sugar.Infof(m)
+		h.slackClient.PostMessage(channel, fmt.Sprintf("%s\n%s", m, *u), slack.PostMessageParameters{})
+	}
+}
 
-		go func() {
-			if strings.HasSuffix(service.InfoPlistPath, "Info.plist") {
-				infoPlist.SetVersion(parameters.Version, parameters.BuildNumber)
-				bytes, _ = infoPlist.serialized()
-			} else {
-				bytes = []byte(fmt.Sprintf("APP_VERSION = %s\nBUILD_VERSION = %s", parameters.Version, parameters.BuildNumber))
-			}
+// interactionPayload is the payload of a block_actions, view_submission or
+// shortcut interaction.
+// https://api.slack.com/reference/interaction-payloads
+type interactionPayload struct {
+	Type        string `json:"type"`
+	Token       string `json:"token"`
+	CallbackID  string `json:"callback_id"`
+	TriggerID   string `json:"trigger_id"`
+	ResponseURL string `json:"response_url"`
+	User        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message interactionMessage `json:"message"`
+	Actions []blockAction      `json:"actions"`
+	View    submittedView      `json:"view"`
+}
 
-			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-			commitBranch := fmt.Sprintf("%s/%s-%s-%s", branchPrefix(action.Name), parameters.Version, parameters.BuildNumber, timestamp)
-			title := fmt.Sprintf("Release %s (%s)", parameters.Version, parameters.BuildNumber)
+type blockAction struct {
+	ActionID       string `json:"action_id"`
+	BlockID        string `json:"block_id"`
+	Type           string `json:"type"`
+	Value          string `json:"value"`
+	SelectedOption struct {
+		Value string `json:"value"`
+	} `json:"selected_option"`
+}
 
-			changelog := generateChangeLog(service, parameters.Version, parameters.Branch)
+// interactionMessage is the message an interaction came from.
+type interactionMessage struct {
+	Ts     string `json:"ts"`
+	Blocks []struct {
+		BlockID  string `json:"block_id"`
+		Elements []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"elements"`
+	} `json:"blocks"`
+}
 
-			commitMessage := fmt.Sprintf("%s", changelog)
+// actionKey identifies the action on the message, so that the action
+// delivered twice, e.g. by a double click, is handled once.
+func (payload interactionPayload) actionKey() string {
+	action := payload.Actions[0]
+	hash := sha256.Sum256([]byte(action.BlockID + "/" + action.ActionID + "/" + action.Value + "/" + action.SelectedOption.Value))
+	return fmt.Sprintf("action/%s/%s/%x", payload.Channel.ID, payload.Message.Ts, hash)
+}
 
-			u, err := service.PushPullRequest(PullRequest{
-				TargetBranch:  parameters.Branch,
-				CommitBranch:  commitBranch,
-				FileContent:   bytes,
-				FilePath:      service.InfoPlistPath,
-				Title:         title,
-				CommitMessage: commitMessage,
-			})
-			if err != nil {
-				e := fmt.Errorf("failed to create pull request %s", err)
-				sugar.Error(e)
-				h.slackClient.PostMessage(message.Channel.ID, fmt.Sprintf("%s", e), slack.PostMessageParameters{})
-			} else {
-				m := fmt.Sprintf("Releasing `%s (%s)`", parameters.Version, parameters.BuildNumber)
-				sugar.Infof(m)
-				h.slackClient.PostMessage(message.Channel.ID, fmt.Sprintf("%s\n%s", m, *u), slack.PostMessageParameters{})
+// sessionID returns the session ID kept in the cancel button of the block.
+func (message interactionMessage) sessionID(blockID string) string {
+	for _, block := range message.Blocks {
+		if block.BlockID != blockID {
+			continue
+		}
+		for _, element := range block.Elements {
+			if element.ActionID == actionCancel {
+				return element.Value
 			}
-		}()
-	case actionCancel:
-		responseMessage(w, message.OriginalMessage, fmt.Sprintf("Operation canceled by '%s'.", message.User.Name), "")
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+		}
 	}
+	return ""
 }
 
-func responseMessage(w http.ResponseWriter, original slack.Message, title, value string) {
-	original.Attachments[0].Actions = []slack.AttachmentAction{}
-	original.Attachments[0].Fields = []slack.AttachmentField{
-		{
-			Title: title,
-			Value: value,
-			Short: false,
-		},
+func respondMessage(responseURL string, title, value string) {
+	blocks := []block{sectionBlock(fmt.Sprintf("*%s*", title))}
+	if value != "" {
+		blocks = append(blocks, contextBlock(value))
+	}
+	if err := respond(responseURL, title, blocks); err != nil {
+		sugar.Error(err)
 	}
-
-	w.Header().Add("Content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(&original)
 }
 
-func responseAction(w http.ResponseWriter, original slack.Message, text string, actions []slack.AttachmentAction) {
-	original.Attachments[0].Text = text
-	original.Attachments[0].Actions = actions
+func respondAction(responseURL string, text string, actions []blockElement) {
+	blocks := []block{
+		sectionBlock(text),
+		actionsBlock(callbackID, actions),
+	}
+	if err := respond(responseURL, text, blocks); err != nil {
+		sugar.Error(err)
+	}
+}
 
-	w.Header().Add("Content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(&original)
+func respondError(responseURL string, title, value string) {
+	blocks := []block{sectionBlock(fmt.Sprintf(":x: *%s*", title))}
+	if value != "" {
+		blocks = append(blocks, contextBlock(value))
+	}
+	if err := respond(responseURL, title, blocks); err != nil {
+		sugar.Error(err)
+	}
 }
 
-func responseError(w http.ResponseWriter, original slack.Message, title, value string) {
-	original.Attachments[0].Actions = []slack.AttachmentAction{}
-	original.Attachments[0].Fields = []slack.AttachmentField{
-		{
-			Title: title,
-			Value: value,
-			Short: false,
-		},
+// maxVersionButtons is the number of version buttons shown next to the
+// cancel button. The rest of the versions are put in a select menu to keep
+// the message short.
+const maxVersionButtons = 4
+
+func versionOptions(parameters BuildParameters, sessionID string) []blockElement {
+	primary := parameters.CurrentVersion
+	if parameters.RecommendedVersion != "" {
+		primary = parameters.RecommendedVersion
 	}
 
-	w.Header().Add("Content-type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(&original)
-}
+	buttons := []string{parameters.CurrentVersion}
+	for _, version := range parameters.NextVersions {
+		// Keep the recommended version out of the select menu.
+		if version == primary {
+			buttons = append(buttons[:1], append([]string{version}, buttons[1:]...)...)
+		} else {
+			buttons = append(buttons, version)
+		}
+	}
+	var others []string
+	if len(buttons) > maxVersionButtons {
+		buttons, others = buttons[:maxVersionButtons-1], buttons[maxVersionButtons-1:]
+	}
 
-func versionOptions(parameters BuildParameters) []slack.AttachmentAction {
-	parameters.Version = parameters.CurrentVersion
-	currentVersionAction := slack.AttachmentAction{
-		Name:  actionVersion,
-		Text:  parameters.Version,
-		Value: parameters.string(),
-		Type:  "button",
-		Style: "primary",
-	}
-
-	parameters.Version = parameters.NextPatch
-	patchVersionAction := slack.AttachmentAction{
-		Name:  actionVersion,
-		Text:  parameters.Version,
-		Value: parameters.string(),
-		Type:  "button",
-	}
-
-	parameters.Version = parameters.NextMinor
-	minorVersionAction := slack.AttachmentAction{
-		Name:  actionVersion,
-		Text:  parameters.Version,
-		Value: parameters.string(),
-		Type:  "button",
-	}
-
-	parameters.Version = parameters.NextMajor
-	majorVersionAction := slack.AttachmentAction{
-		Name:  actionVersion,
-		Text:  parameters.Version,
-		Value: parameters.string(),
-		Type:  "button",
-	}
-	actions := []slack.AttachmentAction{
-		currentVersionAction,
-		patchVersionAction,
-		minorVersionAction,
-		majorVersionAction,
-		cancelAction(),
+	var actions []blockElement
+	for _, version := range buttons {
+		style := ""
+		if version == primary {
+			style = "primary"
+		}
+		actions = append(actions, buttonElement(actionVersion, version, version, style))
 	}
+
+	if len(others) > 0 {
+		var options []optionObject
+		for _, version := range others {
+			options = append(options, option(version, version))
+		}
+		actions = append(actions, selectElement(actionVersion, "Other versions", options))
+	}
+
+	actions = append(actions, cancelAction(sessionID))
 	return actions
 }
 
-func buildNumberOptions(parameters BuildParameters) []slack.AttachmentAction {
-	parameters.BuildNumber = parameters.NextBuildNumber
-	currentBuildNumberAction := slack.AttachmentAction{
-		Name:  actionBuildNumber,
-		Text:  parameters.BuildNumber,
-		Value: parameters.string(),
-		Type:  "button",
-		Style: "primary",
-	}
-
-	nextBuildNumber := parameters.NextBuildNumber
-	number, _ := strconv.Atoi(nextBuildNumber)
-	number++
-	var options []slack.AttachmentActionOption
-	for i := number; i <= number+5; i++ {
-		buildNumber := strconv.Itoa(i)
-		parameters.BuildNumber = buildNumber
-		options = append(options, slack.AttachmentActionOption{
-			Text:  buildNumber,
-			Value: parameters.string(),
-		})
+// recommendVersion picks the next version from the Conventional Commits on
+// the branch since the latest tag, and explains why. Nothing is recommended
+// if the commits can not be read or the scheme has no bumps.
+func recommendVersion(app *App, scheme versioningScheme, versionFile VersionFile, branch string, nextVersions []string) (string, string) {
+	if scheme.bump == nil {
+		return "", ""
 	}
-	actions := []slack.AttachmentAction{
-		currentBuildNumberAction,
-		{
-			Name:    actionBuildNumber,
-			Text:    "Build number",
-			Type:    "select",
-			Options: options,
-		},
-		cancelAction(),
+	messages, err := commitsSinceLatestTag(app.Service, branch)
+	if err != nil {
+		sugar.Warnf("Failed to read commits since the latest tag: %s", err)
+		return "", ""
 	}
-	return actions
+	summary := summarizeCommits(messages)
+	if summary.bump() == "" {
+		return "", ""
+	}
+	version, err := scheme.bump(versionFile, summary.bump())
+	if err != nil || !containsString(nextVersions, version) {
+		return "", ""
+	}
+	return version, summary.String()
 }
 
-func runOptions(parameters BuildParameters) []slack.AttachmentAction {
-	releaseAction := slack.AttachmentAction{
-		Name:  actionRelease,
-		Text:  " TestFlight ⚙ Beta",
-		Value: parameters.string(),
-		Type:  "button",
-		Style: "primary",
-	}
-	externalAction := slack.AttachmentAction{
-		Name:  actionExternal,
-		Text:  " TestFlight",
-		Value: parameters.string(),
-		Type:  "button",
-	}
-	internalAction := slack.AttachmentAction{
-		Name:  actionInternal,
-		Text:  "⚙ Fabric Beta",
-		Value: parameters.string(),
-		Type:  "button",
-	}
-	actions := []slack.AttachmentAction{
-		releaseAction,
-		externalAction,
-		internalAction,
-		cancelAction(),
+func buildNumberOptions(parameters BuildParameters, sessionID string) []blockElement {
+	buildNumber := parameters.NextBuildNumber
+	var options []optionObject
+	for i := 0; i < 6; i++ {
+		next, err := nextBuildNumber(buildNumber)
+		if err != nil {
+			break
+		}
+		buildNumber = next
+		options = append(options, option(buildNumber, buildNumber))
 	}
-	return actions
-}
 
-func cancelAction() slack.AttachmentAction {
-	return slack.AttachmentAction{
-		Name:  actionCancel,
-		Text:  "Cancel",
-		Value: "cancel",
-		Type:  "button",
-		Style: "danger",
+	actions := []blockElement{
+		buttonElement(actionBuildNumber, parameters.NextBuildNumber, parameters.NextBuildNumber, "primary"),
+	}
+	if len(options) > 0 {
+		actions = append(actions, selectElement(actionBuildNumber, "Build number", options))
 	}
+	actions = append(actions, cancelAction(sessionID))
+	return actions
 }
 
-func branchPrefix(actionName string) string {
-	switch actionName {
-	case actionRelease:
-		return "_release"
-	case actionExternal:
-		return "_testflight"
-	case actionInternal:
-		return "_fabric-beta"
+func runOptions(app *App, parameters BuildParameters, sessionID string) []blockElement {
+	var actions []blockElement
+	for i, destination := range app.DestinationsFor(parameters.Version) {
+		style := ""
+		if i == 0 {
+			style = "primary"
+		}
+		actions = append(actions, buttonElement(actionDestination, destination.Label, destination.Name, style))
 	}
-	return "null"
+	actions = append(actions, cancelAction(sessionID))
+	return actions
 }
 
-func destination(actionName string) string {
-	switch actionName {
-	case actionRelease:
-		return "TestFlight and Beta"
-	case actionExternal:
-		return "TestFlight"
-	case actionInternal:
-		return "Fabric Beta"
-	}
-	return "Unknown"
+// overrideOptions asks to release a version that is not higher than the
+// versions already released.
+func overrideOptions(parameters BuildParameters, sessionID string) []blockElement {
+	override := buttonElement(actionOverride, "Release anyway", "", "danger")
+	override.Confirm = confirmDialog("Release anyway?", fmt.Sprintf("Release %s (%s) anyway?", parameters.Version, parameters.BuildNumber), "Release")
+	return []blockElement{override, cancelAction(sessionID)}
 }
 
-func generateChangeLog(service *GitHubService, nextVersion string, branch string) string {
-	return ""
-	//latestTag, err := service.LatestTag()
-	//if err != nil {
-	//	return ""
-	//}
-	//commits, err := service.Commits(*latestTag.Name, branch)
-	//if err != nil {
-	//	return ""
-	//}
-	//
-	//changelog := []string{}
-	//for _, commit := range commits {
-	//	var message = *commit.Commit.Message
-	//	if *commit.Commit.Committer.Name == "GitHub" && strings.HasPrefix(message, "Merge pull request") {
-	//		message = ":twisted_rightwards_arrows: " + strings.Join(strings.Fields(message)[:4], " ")
-	//	}
-	//	log := fmt.Sprintf("* %s [%s](%s) ([%s](%s))", strings.Split(message, "\n")[0], (*commit.SHA)[:7], *commit.HTMLURL, *commit.Author.Login, *commit.Author.HTMLURL)
-	//	changelog = append([]string{log}, changelog...)
-	//}
-	//
-	//section := fmt.Sprintf("## [%s](https://github.com/%s/%s/compare/%s...%s) (%s)", nextVersion, service.Repository.Owner, service.Repository.Name, *latestTag.Name, branch, time.Now().Format("2006-01-02"))
-	//changelog = append([]string{section}, changelog...)
-	//
-	//return strings.Join(changelog, "\n")
+// cancelAction cancels the operation. It also keeps the ID of the session for
+// the actions next to it.
+func cancelAction(sessionID string) blockElement {
+	return buttonElement(actionCancel, "Cancel", sessionID, "danger")
 }
@@ -7,12 +7,12 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"os"
+	"time"
 )
 
 var (
-	logger  *zap.Logger
-	sugar   *zap.SugaredLogger
-	service *GitHubService
+	logger *zap.Logger
+	sugar  *zap.SugaredLogger
 )
 
 func main() {
@@ -33,29 +33,59 @@ func _main(_ []string) int {
 		// load config
 		config, err := LoadConfig(c.String("config"), c.String("region"))
 		if err != nil {
-			return fmt.Errorf("failed to load toml file: %s", err)
+			return fmt.Errorf("failed to load config: %s", err)
 		}
 
-		// FIXME: Pass Version.xcconfig path from env
-		config.InfoPlistPath = "Configurations/Version.xcconfig"
+		apps := NewApps(config)
 
-		repo := GitHubRepository{Owner: config.GitHubRepositoryOwner, Name: config.GitHubRepositoryName}
-		author := CommitAuthor{Name: config.GitCommitAuthorName, Email: config.GitCommitAuthorEmail}
-		service = NewGitHubService(config.GitHubToken, repo, author, config.InfoPlistPath)
+		sessions, err := openSessionStore(config.SessionStore, config.SessionPath)
+		if err != nil {
+			return fmt.Errorf("failed to open session store: %s", err)
+		}
 
-		sugar.Infof("Start slack event listening")
 		client := slack.New(config.BotToken)
 		slackListener := &SlackListener{
 			client:         client,
+			api:            slackAPI{token: config.BotToken},
+			sessions:       sessions,
 			botID:          config.BotID,
 			channelID:      config.ChannelID,
 			debugChannelID: config.DebugChannelID,
+			apps:           apps,
 		}
-		go slackListener.ListenAndResponse()
-
-		http.Handle("/interaction", interactionHandler{
-			slackClient:       client,
+		verifier := slackVerifier{
+			signingSecret:     config.SigningSecret,
 			verificationToken: config.VerificationToken,
+			now:               time.Now,
+		}
+
+		deliveries := newDeliveries(deliveryTTL)
+
+		if config.SlackMode == slackModeRTM {
+			sugar.Infof("Start slack event listening")
+			go slackListener.ListenAndResponse()
+		} else {
+			http.Handle("/events", eventsHandler{
+				verifier:   verifier,
+				listener:   slackListener,
+				deliveries: deliveries,
+			})
+		}
+
+		interaction := interactionHandler{
+			slackClient: client,
+			api:         slackAPI{token: config.BotToken},
+			sessions:    sessions,
+			deliveries:  deliveries,
+			verifier:    verifier,
+			apps:        apps,
+			now:         time.Now,
+		}
+		http.Handle("/interaction", interaction)
+		http.Handle("/command", slashCommandHandler{
+			verifier:    verifier,
+			listener:    slackListener,
+			interaction: interaction,
 		})
 
 		sugar.Infof("Server listening on :%s", c.String("port"))
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+const (
+	formCallbackID    = "release_form"
+	appFormCallbackID = "release_app"
+
+	formNotes    = "notes"
+	formOverride = "override"
+)
+
+// releaseForm is kept in the private metadata of the release form.
+type releaseForm struct {
+	App     string `json:"app"`
+	Channel string `json:"channel"`
+}
+
+// submittedView is the modal of a view_submission interaction.
+type submittedView struct {
+	ID              string `json:"id"`
+	Hash            string `json:"hash"`
+	CallbackID      string `json:"callback_id"`
+	PrivateMetadata string `json:"private_metadata"`
+	State           struct {
+		Values map[string]map[string]viewValue `json:"values"`
+	} `json:"state"`
+}
+
+type viewValue struct {
+	Value           string         `json:"value"`
+	SelectedOption  *optionObject  `json:"selected_option"`
+	SelectedOptions []optionObject `json:"selected_options"`
+}
+
+// value returns the value entered or selected in the input.
+func (v submittedView) value(id string) string {
+	value := v.State.Values[id][id]
+	if value.SelectedOption != nil {
+		return value.SelectedOption.Value
+	}
+	return strings.TrimSpace(value.Value)
+}
+
+func (v submittedView) checked(id string) bool {
+	return len(v.State.Values[id][id].SelectedOptions) > 0
+}
+
+func (v submittedView) form() releaseForm {
+	var form releaseForm
+	json.Unmarshal([]byte(v.PrivateMetadata), &form)
+	return form
+}
+
+func (form releaseForm) string() string {
+	bytes, _ := json.Marshal(form)
+	return string(bytes)
+}
+
+func messageView(text string) view {
+	return view{
+		Type:   "modal",
+		Title:  plainText("Release"),
+		Close:  plainText("Close"),
+		Blocks: []block{sectionBlock(text)},
+	}
+}
+
+// openShortcut opens the release form from a shortcut. A global shortcut has
+// no channel, so the result of the release is sent to the user instead.
+func (h interactionHandler) openShortcut(payload interactionPayload) {
+	if payload.CallbackID != callbackID {
+		sugar.Errorf("Unknown shortcut: %s", payload.CallbackID)
+		return
+	}
+
+	apps := h.apps
+	channel := payload.Channel.ID
+	if channel != "" {
+		apps = h.apps.ForChannel(channel)
+	} else {
+		channel = payload.User.ID
+	}
+
+	switch len(apps) {
+	case 0:
+		if _, err := h.api.openView(payload.TriggerID, messageView("No app can be delivered from this channel.")); err != nil {
+			sugar.Errorf("Failed to open the release form: %s", err)
+		}
+	case 1:
+		h.openReleaseForm(payload.TriggerID, apps[0], channel)
+	default:
+		var options []optionObject
+		for _, app := range apps {
+			options = append(options, option(app.Name, app.Name))
+		}
+		v := view{
+			Type:            "modal",
+			CallbackID:      appFormCallbackID,
+			Title:           plainText("Release"),
+			Submit:          plainText("Next"),
+			Close:           plainText("Cancel"),
+			PrivateMetadata: releaseForm{Channel: channel}.string(),
+			Blocks: []block{
+				inputBlock(actionApp, "App", selectElement("", "Select app...", options)),
+			},
+		}
+		if _, err := h.api.openView(payload.TriggerID, v); err != nil {
+			sugar.Errorf("Failed to open the release form: %s", err)
+		}
+	}
+}
+
+// openReleaseForm opens the release form while it is being loaded, since the
+// trigger expires before the version files are read.
+func (h interactionHandler) openReleaseForm(triggerID string, app *App, channel string) {
+	viewID, err := h.api.openView(triggerID, messageView(fmt.Sprintf("Loading %s ...", app.Name)))
+	if err != nil {
+		sugar.Errorf("Failed to open the release form: %s", err)
+		return
+	}
+	go h.updateReleaseForm(viewID, app, channel)
+}
+
+func (h interactionHandler) updateReleaseForm(viewID string, app *App, channel string) {
+	v, err := h.releaseFormView(app, channel)
+	if err != nil {
+		v = messageView(fmt.Sprintf(":x: %s", err))
+	}
+	if err := h.api.updateView(viewID, "", v); err != nil {
+		sugar.Errorf("Failed to update the release form: %s", err)
+	}
+}
+
+// loadReleaseForm replaces the loading view of a submitted app form with the
+// release form. The hash of the submitted view keeps the update from
+// replacing a view that is newer than the loading view. A hash conflict
+// means the loading view is shown already, so the form replaces it then.
+func (h interactionHandler) loadReleaseForm(submitted submittedView, app *App, channel string) {
+	v, err := h.releaseFormView(app, channel)
+	if err != nil {
+		v = messageView(fmt.Sprintf(":x: %s", err))
+	}
+	err = h.api.updateView(submitted.ID, submitted.Hash, v)
+	if apiErr, ok := err.(slackAPIError); ok && apiErr.code == "hash_conflict" {
+		err = h.api.updateView(submitted.ID, "", v)
+	}
+	if err != nil {
+		sugar.Errorf("Failed to update the release form: %s", err)
+	}
+}
+
+// releaseFormView returns the release form filled in with the next version
+// and build number on the default branch.
+func (h interactionHandler) releaseFormView(app *App, channel string) (view, error) {
+	defaultBranch, err := app.Service.DefaultBranch()
+	if err != nil {
+		return view{}, err
+	}
+	branches, err := app.Service.Branches()
+	if err != nil {
+		return view{}, err
+	}
+
+	versionFile, err := LoadVersionFiles(app.VersionFiles, func(path string) ([]byte, error) {
+		return app.Service.File(*defaultBranch, path)
+	})
+	if err != nil {
+		return view{}, err
+	}
+	if mismatches := versionFile.Mismatches(); len(mismatches) > 0 {
+		return view{}, fmt.Errorf("version files do not match: %s", strings.Join(mismatches, "; "))
+	}
+
+	scheme := versioningSchemes[app.VersioningScheme]
+	nextVersions, err := scheme.next(versionRequest{
+		file:     versionFile,
+		channels: app.Channels(),
+		format:   app.CalVerFormat,
+		now:      h.now(),
+	})
+	if err != nil {
+		return view{}, err
+	}
+	version, recommendation := recommendVersion(app, scheme, versionFile, *defaultBranch, nextVersions)
+	if version == "" && len(nextVersions) > 0 {
+		version = nextVersions[0]
+	}
+
+	strategy := buildNumberStrategies[app.BuildNumberStrategy]
+	nextBuildNumber, _ := versionFile.NextBuildNumber()
+	buildNumber, err := strategy.next(buildNumberRequest{
+		app:                app,
+		branch:             *defaultBranch,
+		currentVersion:     versionFile.Version(),
+		currentBuildNumber: versionFile.BuildNumber(),
+		nextBuildNumber:    nextBuildNumber,
+		version:            version,
+		now:                h.now(),
+	})
+	if err != nil {
+		sugar.Warnf("Failed to pick the build number: %s", err)
+		buildNumber = ""
+	}
+
+	branchOptions := []optionObject{option(*defaultBranch, *defaultBranch)}
+	for _, branch := range branches {
+		if branch.GetName() != *defaultBranch {
+			branchOptions = append(branchOptions, option(branch.GetName(), branch.GetName()))
+		}
+	}
+	branch := selectElement("", "Select branch...", branchOptions)
+	branch.InitialOption = &branchOptions[0]
+
+	var destinationOptions []optionObject
+	for _, d := range app.Destinations {
+		destinationOptions = append(destinationOptions, option(d.Label, d.Name))
+	}
+	destination := selectElement("", "Select destination...", destinationOptions)
+	if destinations := app.DestinationsFor(version); len(destinations) > 0 {
+		initial := option(destinations[0].Label, destinations[0].Name)
+		destination.InitialOption = &initial
+	}
+
+	versionHint := fmt.Sprintf("Next versions: %s", strings.Join(nextVersions, ", "))
+	if recommendation != "" {
+		versionHint = fmt.Sprintf("%s (%s)", versionHint, recommendation)
+	}
+
+	branchInput := inputBlock(actionBranch, "Branch", branch)
+	branchInput.Hint = plainText(fmt.Sprintf("The version and build number are computed from %s.", *defaultBranch))
+	versionInput := inputBlock(actionVersion, "Version", textInputElement(version, false))
+	versionInput.Hint = plainText(versionHint)
+	buildNumberInput := inputBlock(actionBuildNumber, "Build number", textInputElement(buildNumber, false))
+	buildNumberInput.Hint = plainText(fmt.Sprintf("Strategy: %s", strategy.description))
+	notesInput := inputBlock(formNotes, "Notes", textInputElement("", true))
+	notesInput.Hint = plainText("Added to the pull request and the release notes.")
+	notesInput.Optional = true
+	overrideInput := inputBlock(formOverride, "Version guard", checkboxesElement([]optionObject{
+		option("Release anyway", formOverride),
+	}))
+	overrideInput.Hint = plainText("Releases the version even if it is not higher than the released ones.")
+	overrideInput.Optional = true
+
+	return view{
+		Type:            "modal",
+		CallbackID:      formCallbackID,
+		Title:           plainText("Release"),
+		Submit:          plainText("Release"),
+		Close:           plainText("Cancel"),
+		PrivateMetadata: releaseForm{App: app.Name, Channel: channel}.string(),
+		Blocks: []block{
+			sectionBlock(fmt.Sprintf("App: `%s`\nCurrent Version: `%s (%s)`", app.Name, versionFile.Version(), versionFile.BuildNumber())),
+			branchInput,
+			versionInput,
+			buildNumberInput,
+			inputBlock(actionDestination, "Destination", destination),
+			notesInput,
+			overrideInput,
+		},
+	}, nil
+}
+
+func (h interactionHandler) submitView(w http.ResponseWriter, payload interactionPayload) error {
+	form := payload.View.form()
+
+	switch payload.View.CallbackID {
+	case appFormCallbackID:
+		app := h.apps.Find(payload.View.value(actionApp))
+		if app == nil {
+			respondViewErrors(w, map[string]string{actionApp: "Unknown app."})
+			return fmt.Errorf("unknown app: %s", payload.View.value(actionApp))
+		}
+		// Reading the version files takes longer than Slack waits for the
+		// response, so the form is loaded after it.
+		respondViewUpdate(w, messageView(fmt.Sprintf("Loading %s ...", app.Name)))
+		go h.loadReleaseForm(payload.View, app, form.Channel)
+		return nil
+	case formCallbackID:
+		app := h.apps.Find(form.App)
+		if app == nil {
+			sugar.Errorf("Unknown app: %s", form.App)
+			w.WriteHeader(http.StatusBadRequest)
+			return fmt.Errorf("unknown app: %s", form.App)
+		}
+		return h.submitReleaseForm(w, payload.View, app, form.Channel)
+	default:
+		sugar.Errorf("Unknown view: %s", payload.View.CallbackID)
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("unknown view: %s", payload.View.CallbackID)
+	}
+}
+
+// submitReleaseForm validates the release form and releases the version.
+// Invalid fields are reported in the form. The version files and released
+// versions are checked after the response, since reading them may take longer
+// than Slack waits for it. An error is returned if the form is rejected.
+func (h interactionHandler) submitReleaseForm(w http.ResponseWriter, v submittedView, app *App, channel string) error {
+	parameters := BuildParameters{
+		App:         app.Name,
+		Branch:      v.value(actionBranch),
+		Version:     v.value(actionVersion),
+		BuildNumber: v.value(actionBuildNumber),
+		Destination: v.value(actionDestination),
+		Override:    v.checked(formOverride),
+		Notes:       v.value(formNotes),
+	}
+
+	destination, errors := validateRelease(app, parameters)
+	if len(errors) > 0 {
+		respondViewErrors(w, errors)
+		return fmt.Errorf("invalid release form")
+	}
+	respondViewUpdate(w, messageView(fmt.Sprintf("Checking %s `%s (%s)` on `%s` ...", app.Name, parameters.Version, parameters.BuildNumber, parameters.Branch)))
+
+	go func() {
+		versionFile, err := loadRelease(app, parameters)
+		if err != nil {
+			if err := h.api.updateView(v.ID, "", messageView(fmt.Sprintf(":x: %s", err))); err != nil {
+				sugar.Errorf("Failed to update the release form: %s", err)
+			}
+			return
+		}
+
+		text := fmt.Sprintf("Releasing %s `%s (%s)` to %s ...", app.Name, parameters.Version, parameters.BuildNumber, destination.Description)
+		if err := h.api.updateView(v.ID, "", messageView(text)); err != nil {
+			sugar.Errorf("Failed to update the release form: %s", err)
+		}
+		h.slackClient.PostMessage(channel, text, slack.PostMessageParameters{})
+		h.release(channel, app, destination, parameters, versionFile)
+	}()
+	return nil
+}
+
+// releaseFields are the fields validateRelease reports errors for, in order.
+var releaseFields = []string{actionVersion, actionBuildNumber, actionDestination}
+
+// validateRelease validates the parameters of a release entered by hand.
+// Errors are returned by the field they are found in.
+func validateRelease(app *App, parameters BuildParameters) (*Destination, map[string]string) {
+	errors := map[string]string{}
+	if err := versioningSchemes[app.VersioningScheme].validate(parameters.Version, app.CalVerFormat); err != nil {
+		errors[actionVersion] = err.Error()
+	}
+	if _, err := nextBuildNumber(parameters.BuildNumber); err != nil {
+		errors[actionBuildNumber] = err.Error()
+	}
+	destination := app.Destination(parameters.Destination)
+	if destination == nil {
+		errors[actionDestination] = fmt.Sprintf("Unknown destination: %s", parameters.Destination)
+	} else if _, ok := errors[actionVersion]; !ok && !destination.Accepts(parameters.Version) {
+		errors[actionDestination] = fmt.Sprintf("%s does not accept %s versions.", destination.Description, versionChannel(parameters.Version))
+	}
+	return destination, errors
+}
+
+// loadRelease loads the version files of the branch of a release entered by
+// hand, and checks that the version is higher than the released ones unless
+// it is overridden.
+func loadRelease(app *App, parameters BuildParameters) (*VersionFileSet, error) {
+	versionFile, err := LoadVersionFiles(app.VersionFiles, func(path string) ([]byte, error) {
+		return app.Service.File(parameters.Branch, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mismatches := versionFile.Mismatches(); len(mismatches) > 0 {
+		return nil, fmt.Errorf("version files do not match: %s", strings.Join(mismatches, "; "))
+	}
+
+	if !parameters.Override {
+		release, err := findShippedRegression(app, parameters.Branch, parameters.Version, parameters.BuildNumber)
+		if err != nil {
+			return nil, fmt.Errorf("released versions can not be read: %s", err)
+		}
+		if release != nil {
+			shipped := fmt.Sprintf("%s (%s)", release.version, release.buildNumber)
+			if release.buildNumber == "" {
+				shipped = release.version
+			}
+			return nil, fmt.Errorf("%s (%s) is not higher than %s of %s, so the store will reject the build", parameters.Version, parameters.BuildNumber, shipped, release.source)
+		}
+	}
+
+	return versionFile, nil
+}
+
+func respondViewErrors(w http.ResponseWriter, errors map[string]string) {
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"response_action": "errors",
+		"errors":          errors,
+	})
+}
+
+func respondViewUpdate(w http.ResponseWriter, v view) {
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"response_action": "update",
+		"view":            v,
+	})
+}
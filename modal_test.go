@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLoadReleaseForm(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		hashes    []string
+	}{
+		{name: "updated", responses: []string{`{"ok": true}`}, hashes: []string{"156772938.1827394"}},
+		// The loading view was shown before the update.
+		{name: "hash conflict", responses: []string{`{"ok": false, "error": "hash_conflict"}`, `{"ok": true}`}, hashes: []string{"156772938.1827394", ""}},
+		{name: "closed", responses: []string{`{"ok": false, "error": "not_found"}`}, hashes: []string{"156772938.1827394"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var hashes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					ViewID string `json:"view_id"`
+					Hash   string `json:"hash"`
+				}
+				json.NewDecoder(r.Body).Decode(&request)
+				if request.ViewID != "V1" {
+					t.Errorf("view_id = %q, want %q", request.ViewID, "V1")
+				}
+				hashes = append(hashes, request.Hash)
+				w.Write([]byte(test.responses[len(hashes)-1]))
+			}))
+			defer server.Close()
+			h := interactionHandler{api: slackAPI{url: server.URL + "/"}}
+			// The repository can not be read, so the form shows the error.
+			app := &App{Name: "ios", Service: testGitHubService(t, http.NotFoundHandler())}
+
+			h.loadReleaseForm(submittedView{ID: "V1", Hash: "156772938.1827394"}, app, "C1")
+
+			if !reflect.DeepEqual(hashes, test.hashes) {
+				t.Errorf("hashes = %q, want %q", hashes, test.hashes)
+			}
+		})
+	}
+}
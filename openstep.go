@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	openStepString = iota
+	openStepData
+	openStepArray
+	openStepDictionary
+)
+
+// openStepValue is a value in an OpenStep property list together with its
+// position in the source, so that it can be replaced without touching the
+// rest of the file.
+type openStepValue struct {
+	kind  int
+	start int
+	end   int
+
+	str     string
+	array   []*openStepValue
+	entries []openStepEntry
+}
+
+type openStepEntry struct {
+	key   string
+	value *openStepValue
+}
+
+// Get returns the value of key in a dictionary.
+func (v *openStepValue) Get(key string) *openStepValue {
+	if v == nil || v.kind != openStepDictionary {
+		return nil
+	}
+	for _, entry := range v.entries {
+		if entry.key == key {
+			return entry.value
+		}
+	}
+	return nil
+}
+
+// String returns the string value or an empty string if v is not a string.
+func (v *openStepValue) String() string {
+	if v == nil || v.kind != openStepString {
+		return ""
+	}
+	return v.str
+}
+
+type openStepParser struct {
+	src []byte
+	pos int
+}
+
+func parseOpenStep(src []byte) (*openStepValue, error) {
+	p := openStepParser{src: src}
+	if bytes.HasPrefix(src, []byte("\xef\xbb\xbf")) {
+		p.pos = 3
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q after the root value", p.src[p.pos])
+	}
+	return value, nil
+}
+
+func (p *openStepParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.src[:p.pos], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip skips whitespace and comments.
+func (p *openStepParser) skip() {
+	for p.pos < len(p.src) {
+		switch {
+		case p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\r' || p.src[p.pos] == '\n':
+			p.pos++
+		case bytes.HasPrefix(p.src[p.pos:], []byte("//")):
+			if i := bytes.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case bytes.HasPrefix(p.src[p.pos:], []byte("/*")):
+			if i := bytes.Index(p.src[p.pos+2:], []byte("*/")); i >= 0 {
+				p.pos += i + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *openStepParser) expect(c byte) error {
+	p.skip()
+	if p.pos >= len(p.src) || p.src[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *openStepParser) value() (*openStepValue, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of file")
+	}
+
+	switch p.src[p.pos] {
+	case '{':
+		return p.dictionary()
+	case '(':
+		return p.array()
+	case '<':
+		return p.data()
+	case '"', '\'':
+		return p.quoted()
+	default:
+		return p.unquoted()
+	}
+}
+
+func (p *openStepParser) dictionary() (*openStepValue, error) {
+	v := openStepValue{kind: openStepDictionary, start: p.pos}
+	p.pos++
+	for {
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			v.end = p.pos
+			return &v, nil
+		}
+
+		key, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if key.kind != openStepString {
+			return nil, p.errorf("dictionary key must be a string")
+		}
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(';'); err != nil {
+			return nil, err
+		}
+		v.entries = append(v.entries, openStepEntry{key: key.str, value: value})
+	}
+}
+
+func (p *openStepParser) array() (*openStepValue, error) {
+	v := openStepValue{kind: openStepArray, start: p.pos}
+	p.pos++
+	for {
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == ')' {
+			p.pos++
+			v.end = p.pos
+			return &v, nil
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		v.array = append(v.array, value)
+
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+func (p *openStepParser) data() (*openStepValue, error) {
+	start := p.pos
+	i := bytes.IndexByte(p.src[p.pos:], '>')
+	if i < 0 {
+		return nil, p.errorf("unterminated data")
+	}
+	p.pos += i + 1
+	return &openStepValue{kind: openStepData, start: start, end: p.pos, str: string(p.src[start:p.pos])}, nil
+}
+
+func (p *openStepParser) quoted() (*openStepValue, error) {
+	start := p.pos
+	quote := p.src[p.pos]
+	p.pos++
+
+	var buf strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == quote {
+			p.pos++
+			return &openStepValue{kind: openStepString, start: start, end: p.pos, str: buf.String()}, nil
+		}
+		if c != '\\' {
+			buf.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated string")
+		}
+		c = p.src[p.pos]
+		p.pos++
+		switch c {
+		case 'a':
+			buf.WriteByte('\a')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case 'v':
+			buf.WriteByte('\v')
+		case 'U', 'u':
+			end := p.pos
+			for end < len(p.src) && end < p.pos+4 && strings.IndexByte("0123456789abcdefABCDEF", p.src[end]) >= 0 {
+				end++
+			}
+			r, err := strconv.ParseUint(string(p.src[p.pos:end]), 16, 32)
+			if err != nil {
+				return nil, p.errorf("invalid unicode escape")
+			}
+			buf.WriteRune(rune(r))
+			p.pos = end
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := p.pos
+			for end < len(p.src) && end < p.pos+2 && p.src[end] >= '0' && p.src[end] <= '7' {
+				end++
+			}
+			r, _ := strconv.ParseUint(string(p.src[p.pos-1:end]), 8, 8)
+			buf.WriteByte(byte(r))
+			p.pos = end
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+var openStepUnquotedPattern = regexp.MustCompile(`^[A-Za-z0-9_$+/:.\-]+$`)
+
+func isOpenStepUnquoted(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_$+/:.-", c) >= 0
+}
+
+func (p *openStepParser) unquoted() (*openStepValue, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isOpenStepUnquoted(p.src[p.pos]) {
+		if bytes.HasPrefix(p.src[p.pos:], []byte("//")) || bytes.HasPrefix(p.src[p.pos:], []byte("/*")) {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return &openStepValue{kind: openStepString, start: start, end: p.pos, str: string(p.src[start:p.pos])}, nil
+}
+
+// quoted reports whether the string value is quoted in the source.
+func (v *openStepValue) quoted(src []byte) bool {
+	return v.kind == openStepString && (src[v.start] == '"' || src[v.start] == '\'')
+}
+
+// quoteOpenStep formats s as an OpenStep string. Unless quoted is true, s is
+// quoted only if needed.
+func quoteOpenStep(s string, quoted bool) string {
+	if !quoted && openStepUnquotedPattern.MatchString(s) && !strings.Contains(s, "//") && !strings.Contains(s, "/*") {
+		return s
+	}
+
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\t':
+			buf.WriteString("\\t")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestParseOpenStep(t *testing.T) {
+	src := []byte("// !$*UTF8*$!\n{\n\t/* comment */ key = value; // comment\n\tquoted = \"a \\\"b\\\"\\n\\U00e9\\101\";\n\tsingle = 'c';\n\tlist = (one, \"two\", );\n\tdata = <0fbd 7710>;\n\tnested = { path = a/b.c; };\n}\n")
+	root, err := parseOpenStep(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"key":    "value",
+		"quoted": "a \"b\"\né" + "A",
+		"single": "c",
+	}
+	for key, want := range tests {
+		if got := root.Get(key).String(); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if list := root.Get("list"); list == nil || len(list.array) != 2 || list.array[1].String() != "two" {
+		t.Errorf("list = %+v", list)
+	}
+	if data := root.Get("data"); data == nil || data.kind != openStepData || data.str != "<0fbd 7710>" {
+		t.Errorf("data = %+v", data)
+	}
+	if got := root.Get("nested").Get("path").String(); got != "a/b.c" {
+		t.Errorf("nested.path = %q", got)
+	}
+
+	// The position of a value covers it with its quotes.
+	quoted := root.Get("quoted")
+	if got := string(src[quoted.start:quoted.end]); got != "\"a \\\"b\\\"\\n\\U00e9\\101\"" || !quoted.quoted(src) {
+		t.Errorf("quoted source = %s", got)
+	}
+}
+
+func TestParseOpenStepErrors(t *testing.T) {
+	tests := map[string]string{
+		"unterminated string":     "{ key = \"value; }",
+		"missing semicolon":       "{ key = value }",
+		"unterminated dictionary": "{ key = value;",
+		"unterminated data":       "{ key = <0fbd; }",
+		"trailing value":          "{ key = value; } extra",
+	}
+	for name, src := range tests {
+		if _, err := parseOpenStep([]byte(src)); err == nil {
+			t.Errorf("%s: parseOpenStep succeeded", name)
+		}
+	}
+}
+
+func TestQuoteOpenStep(t *testing.T) {
+	tests := []struct {
+		s      string
+		quoted bool
+		want   string
+	}{
+		{s: "1.3.0", want: "1.3.0"},
+		{s: "1.3.0", quoted: true, want: `"1.3.0"`},
+		{s: "1.3.0 beta", want: `"1.3.0 beta"`},
+		{s: "a//b", want: `"a//b"`},
+		{s: "say \"hi\"\n", want: `"say \"hi\"\n"`},
+	}
+	for _, test := range tests {
+		if got := quoteOpenStep(test.s, test.quoted); got != test.want {
+			t.Errorf("quoteOpenStep(%q, %v) = %s, want %s", test.s, test.quoted, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const packageJSONVersionKey = "version"
+
+// packageJSONValue is a top-level value in package.json. start and end are
+// the offsets of the value including the quotes of a string.
+type packageJSONValue struct {
+	value  string
+	number bool
+	start  int
+	end    int
+}
+
+// PackageJSON is a version file that keeps the version in package.json. The
+// build number is read from build_number_key if it is configured.
+type PackageJSON struct {
+	path           string
+	raw            []byte
+	versionKey     string
+	buildNumberKey string
+	values         map[string]packageJSONValue
+	updates        map[string]string
+}
+
+func NewPackageJSON(config VersionFileConfig, raw []byte) (*PackageJSON, error) {
+	values, err := packageJSONValues(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{config.VersionKey}
+	if config.BuildNumberKey != "" {
+		keys = append(keys, config.BuildNumberKey)
+	}
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			return nil, fmt.Errorf("%s is not found", key)
+		}
+	}
+
+	packageJSON := PackageJSON{
+		path:           config.Path,
+		raw:            raw,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		values:         values,
+		updates:        map[string]string{},
+	}
+	return &packageJSON, nil
+}
+
+// packageJSONValues returns the string and number values of the top-level
+// object with their positions.
+func packageJSONValues(raw []byte) (map[string]packageJSONValue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	values := map[string]packageJSONValue{}
+	var stack []json.Delim
+	var key string
+	expectingKey := false
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				stack = append(stack, delim)
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+			// The root object has been opened or a nested value in it closed.
+			expectingKey = len(stack) == 1
+			continue
+		}
+		if len(stack) != 1 || stack[0] != '{' {
+			continue
+		}
+		if expectingKey {
+			key = token.(string)
+			expectingKey = false
+			continue
+		}
+		expectingKey = true
+
+		for start < len(raw) && strings.IndexByte(" \t\r\n:", raw[start]) >= 0 {
+			start++
+		}
+		end := int(decoder.InputOffset())
+		switch value := token.(type) {
+		case string:
+			values[key] = packageJSONValue{value: value, start: start, end: end}
+		case json.Number:
+			values[key] = packageJSONValue{value: value.String(), number: true, start: start, end: end}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of JSON")
+	}
+	return values, nil
+}
+
+func (packageJSON *PackageJSON) value(key string) string {
+	if value, ok := packageJSON.updates[key]; ok {
+		return value
+	}
+	return packageJSON.values[key].value
+}
+
+func (packageJSON *PackageJSON) Version() string {
+	return packageJSON.value(packageJSON.versionKey)
+}
+
+func (packageJSON *PackageJSON) BuildNumber() string {
+	if packageJSON.buildNumberKey == "" {
+		return ""
+	}
+	return packageJSON.value(packageJSON.buildNumberKey)
+}
+
+func (packageJSON *PackageJSON) NextMajor() (string, error) {
+	return nextMajor(packageJSON.Version())
+}
+
+func (packageJSON *PackageJSON) NextMinor() (string, error) {
+	return nextMinor(packageJSON.Version())
+}
+
+func (packageJSON *PackageJSON) NextPatch() (string, error) {
+	return nextPatch(packageJSON.Version())
+}
+
+func (packageJSON *PackageJSON) NextBuildNumber() (string, error) {
+	return nextBuildNumber(packageJSON.BuildNumber())
+}
+
+func (packageJSON *PackageJSON) SetVersion(version string, build string) {
+	packageJSON.updates[packageJSON.versionKey] = version
+	if packageJSON.buildNumberKey != "" {
+		packageJSON.updates[packageJSON.buildNumberKey] = build
+	}
+}
+
+func (packageJSON *PackageJSON) Files() (map[string][]byte, error) {
+	var edits []textEdit
+	for key, value := range packageJSON.updates {
+		original := packageJSON.values[key]
+		text := value
+		if !original.number {
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(value); err != nil {
+				return nil, err
+			}
+			text = string(bytes.TrimRight(buf.Bytes(), "\n"))
+		}
+		edits = append(edits, textEdit{start: original.start, end: original.end, text: text})
+	}
+	return map[string][]byte{packageJSON.path: applyTextEdits(packageJSON.raw, edits)}, nil
+}
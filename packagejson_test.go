@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestPackageJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildNumberKey string
+		raw            string
+		version        string
+		buildNumber    string
+		want           string
+	}{
+		{
+			name:    "key order and formatting are kept",
+			raw:     "{\n  \"name\": \"app\",\n  \"version\" :  \"1.2.0\",\n  \"private\": true\n}\n",
+			version: "1.2.0",
+			want:    "{\n  \"name\": \"app\",\n  \"version\" :  \"1.3.0\",\n  \"private\": true\n}\n",
+		},
+		{
+			name: "nested version keys are skipped",
+			raw: `{
+  "name": "app",
+  "engines": {"node": ">=8", "version": "0.1.0"},
+  "workspaces": [{"version": "0.2.0"}],
+  "version": "1.2.0"
+}
+`,
+			version: "1.2.0",
+			want: `{
+  "name": "app",
+  "engines": {"node": ">=8", "version": "0.1.0"},
+  "workspaces": [{"version": "0.2.0"}],
+  "version": "1.3.0"
+}
+`,
+		},
+		{
+			name:           "number build number",
+			buildNumberKey: "buildNumber",
+			raw:            `{"version": "1.2.0", "buildNumber": 42}`,
+			version:        "1.2.0",
+			buildNumber:    "42",
+			want:           `{"version": "1.3.0", "buildNumber": 43}`,
+		},
+		{
+			name:           "string build number",
+			buildNumberKey: "buildNumber",
+			raw:            `{"buildNumber": "42", "version": "1.2.0"}`,
+			version:        "1.2.0",
+			buildNumber:    "42",
+			want:           `{"buildNumber": "43", "version": "1.3.0"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "package.json", VersionKey: packageJSONVersionKey, BuildNumberKey: test.buildNumberKey}
+			packageJSON, err := NewPackageJSON(config, []byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packageJSON.Version() != test.version || packageJSON.BuildNumber() != test.buildNumber {
+				t.Fatalf("got %s (%s), want %s (%s)", packageJSON.Version(), packageJSON.BuildNumber(), test.version, test.buildNumber)
+			}
+
+			packageJSON.SetVersion("1.3.0", "43")
+			files, err := packageJSON.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(files[config.Path]); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPackageJSONErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"name": "app"}`,
+		`{"engines": {"version": "1.2.0"}}`,
+		`{"version": "1.2.0"`,
+	} {
+		config := VersionFileConfig{Path: "package.json", VersionKey: packageJSONVersionKey}
+		if _, err := NewPackageJSON(config, []byte(raw)); err == nil {
+			t.Errorf("NewPackageJSON(%q) succeeded", raw)
+		}
+	}
+}
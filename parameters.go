@@ -1,28 +1,39 @@
 package main
 
-import "encoding/json"
-
 type BuildParameters struct {
+	App         string `json:"app"`
 	Branch      string `json:"branch"`
 	Version     string `json:"version"`
 	BuildNumber string `json:"build_number"`
+	Destination string `json:"destination"`
+	// Override releases the version even if it is not higher than the
+	// versions already released.
+	Override bool `json:"override,omitempty"`
+	// Notes are entered in the release form.
+	Notes string `json:"notes,omitempty"`
 
-	CurrentVersion     string `json:"current_version"`
-	CurrentBuildNumber string `json:"current_build_number"`
-	NextPatch          string `json:"next_patch"`
-	NextMinor          string `json:"next_minor"`
-	NextMajor          string `json:"next_major"`
-	NextBuildNumber    string `json:"next_build_number"`
-	InfoPlist          string `json:"info_plist"`
-}
-
-func NewBuildParameters(jsonStr string) BuildParameters {
-	parameters := new(BuildParameters)
-	json.Unmarshal([]byte(jsonStr), parameters)
-	return *parameters
+	CurrentVersion     string   `json:"current_version"`
+	CurrentBuildNumber string   `json:"current_build_number"`
+	NextVersions       []string `json:"next_versions"`
+	RecommendedVersion string   `json:"recommended_version"`
+	NextBuildNumber    string   `json:"next_build_number"`
 }
 
-func (v BuildParameters) string() string {
-	bytes, _ := json.Marshal(v)
-	return string(bytes[:])
+// with returns the parameters with the value picked for the action.
+func (v BuildParameters) with(action, value string) BuildParameters {
+	switch action {
+	case actionApp:
+		v.App = value
+	case actionBranch:
+		v.Branch = value
+	case actionVersion:
+		v.Version = value
+	case actionBuildNumber:
+		v.BuildNumber = value
+	case actionDestination:
+		v.Destination = value
+	case actionOverride:
+		v.Override = true
+	}
+	return v
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	pbxprojVersionKey     = "MARKETING_VERSION"
+	pbxprojBuildNumberKey = "CURRENT_PROJECT_VERSION"
+)
+
+// pbxprojConfiguration is a build configuration (XCBuildConfiguration) of a
+// target, or of the project itself when target is empty.
+type pbxprojConfiguration struct {
+	target   string
+	name     string
+	settings *openStepValue
+}
+
+func (configuration pbxprojConfiguration) String() string {
+	if configuration.target == "" {
+		return fmt.Sprintf("(project)/%s", configuration.name)
+	}
+	return fmt.Sprintf("%s/%s", configuration.target, configuration.name)
+}
+
+// Pbxproj is a version file that keeps the version and build number in the
+// build settings of an Xcode project (project.pbxproj). The values are
+// rewritten in every selected build configuration that defines them.
+type Pbxproj struct {
+	path           string
+	raw            []byte
+	versionKey     string
+	buildNumberKey string
+
+	targets        []string
+	configurations []pbxprojConfiguration
+	updates        map[string]string
+}
+
+func NewPbxproj(config VersionFileConfig, raw []byte) (*Pbxproj, error) {
+	root, err := parseOpenStep(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := root.Get("objects")
+	project := objects.Get(root.Get("rootObject").String())
+	if project.Get("isa").String() != "PBXProject" {
+		return nil, fmt.Errorf("root object is not found")
+	}
+
+	pbxproj := Pbxproj{
+		path:           config.Path,
+		raw:            raw,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		updates:        map[string]string{},
+	}
+
+	selected := map[string]bool{}
+	for _, name := range config.Targets {
+		selected[name] = false
+	}
+
+	var configurations []pbxprojConfiguration
+	if len(config.Targets) == 0 {
+		configurations = append(configurations, pbxprojConfigurations(objects, project, "")...)
+	}
+	if targets := project.Get("targets"); targets != nil {
+		for _, id := range targets.array {
+			target := objects.Get(id.String())
+			name := target.Get("name").String()
+			pbxproj.targets = append(pbxproj.targets, name)
+
+			if _, ok := selected[name]; len(config.Targets) > 0 && !ok {
+				continue
+			}
+			selected[name] = true
+			configurations = append(configurations, pbxprojConfigurations(objects, target, name)...)
+		}
+	}
+	for name, found := range selected {
+		if !found {
+			return nil, fmt.Errorf("target %q is not found (available: %s)", name, strings.Join(pbxproj.Targets(), ", "))
+		}
+	}
+
+	var available []string
+	for _, configuration := range configurations {
+		if !containsString(available, configuration.name) {
+			available = append(available, configuration.name)
+		}
+		if len(config.Configurations) > 0 && !containsString(config.Configurations, configuration.name) {
+			continue
+		}
+		pbxproj.configurations = append(pbxproj.configurations, configuration)
+	}
+	for _, name := range config.Configurations {
+		if !containsString(available, name) {
+			return nil, fmt.Errorf("build configuration %q is not found (available: %s)", name, strings.Join(available, ", "))
+		}
+	}
+
+	for _, key := range []string{pbxproj.versionKey, pbxproj.buildNumberKey} {
+		if len(pbxproj.assignments(key)) == 0 {
+			return nil, fmt.Errorf("%s is not found in build configurations: %s", key, strings.Join(pbxproj.Configurations(), ", "))
+		}
+	}
+
+	return &pbxproj, nil
+}
+
+// pbxprojConfigurations returns the build configurations in the configuration
+// list of a target or the project.
+func pbxprojConfigurations(objects, owner *openStepValue, target string) []pbxprojConfiguration {
+	var configurations []pbxprojConfiguration
+
+	list := objects.Get(owner.Get("buildConfigurationList").String())
+	if ids := list.Get("buildConfigurations"); ids != nil {
+		for _, id := range ids.array {
+			configuration := objects.Get(id.String())
+			configurations = append(configurations, pbxprojConfiguration{
+				target:   target,
+				name:     configuration.Get("name").String(),
+				settings: configuration.Get("buildSettings"),
+			})
+		}
+	}
+	return configurations
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns the names of all targets in the project.
+func (pbxproj *Pbxproj) Targets() []string {
+	return pbxproj.targets
+}
+
+// Configurations returns the selected build configurations as "Target/Name".
+func (pbxproj *Pbxproj) Configurations() []string {
+	var names []string
+	for _, configuration := range pbxproj.configurations {
+		names = append(names, configuration.String())
+	}
+	return names
+}
+
+// assignments returns the values of key in the selected build configurations.
+// Values that refer to other build settings such as $(inherited) are skipped.
+func (pbxproj *Pbxproj) assignments(key string) []*openStepValue {
+	var values []*openStepValue
+	for _, configuration := range pbxproj.configurations {
+		value := configuration.settings.Get(key)
+		if value == nil || value.kind != openStepString || strings.Contains(value.str, "$(") || strings.Contains(value.str, "${") {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func (pbxproj *Pbxproj) value(key string) string {
+	if value, ok := pbxproj.updates[key]; ok {
+		return value
+	}
+	return pbxproj.assignments(key)[0].str
+}
+
+func (pbxproj *Pbxproj) Version() string {
+	return pbxproj.value(pbxproj.versionKey)
+}
+
+func (pbxproj *Pbxproj) BuildNumber() string {
+	return pbxproj.value(pbxproj.buildNumberKey)
+}
+
+func (pbxproj *Pbxproj) NextMajor() (string, error) {
+	return nextMajor(pbxproj.Version())
+}
+
+func (pbxproj *Pbxproj) NextMinor() (string, error) {
+	return nextMinor(pbxproj.Version())
+}
+
+func (pbxproj *Pbxproj) NextPatch() (string, error) {
+	return nextPatch(pbxproj.Version())
+}
+
+func (pbxproj *Pbxproj) NextBuildNumber() (string, error) {
+	return nextBuildNumber(pbxproj.BuildNumber())
+}
+
+func (pbxproj *Pbxproj) SetVersion(version string, build string) {
+	pbxproj.updates[pbxproj.versionKey] = version
+	pbxproj.updates[pbxproj.buildNumberKey] = build
+}
+
+func (pbxproj *Pbxproj) Files() (map[string][]byte, error) {
+	var edits []textEdit
+	for key, value := range pbxproj.updates {
+		for _, v := range pbxproj.assignments(key) {
+			edits = append(edits, textEdit{start: v.start, end: v.end, text: quoteOpenStep(value, v.quoted(pbxproj.raw))})
+		}
+	}
+	return map[string][]byte{pbxproj.path: applyTextEdits(pbxproj.raw, edits)}, nil
+}
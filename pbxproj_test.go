@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadPbxproj(t *testing.T, targets, configurations []string) (*Pbxproj, []byte, error) {
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "pbxproj", "project.pbxproj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := VersionFileConfig{
+		Path:           "App.xcodeproj/project.pbxproj",
+		VersionKey:     pbxprojVersionKey,
+		BuildNumberKey: pbxprojBuildNumberKey,
+		Targets:        targets,
+		Configurations: configurations,
+	}
+	pbxproj, err := NewPbxproj(config, raw)
+	return pbxproj, raw, err
+}
+
+func TestPbxproj(t *testing.T) {
+	tests := []struct {
+		name           string
+		targets        []string
+		configurations []string
+		selected       []string
+		changed        []string
+	}{
+		{
+			// $(inherited) is skipped, so the build number of App comes
+			// from the project.
+			name:     "all targets",
+			selected: []string{"(project)/Debug", "(project)/Release", "App/Debug", "App/Release", "Widget Extension/Debug", "Widget Extension/Release", "AppTests/Debug", "AppTests/Release"},
+			changed: []string{
+				"CURRENT_PROJECT_VERSION = 43;",
+				"CURRENT_PROJECT_VERSION = 43;",
+				"MARKETING_VERSION = 1.3.0;",
+				"MARKETING_VERSION = 1.3.0;",
+				"CURRENT_PROJECT_VERSION = 43;",
+				"MARKETING_VERSION = 1.3.0;",
+				"CURRENT_PROJECT_VERSION = 43;",
+				`MARKETING_VERSION = "1.3.0";`,
+			},
+		},
+		{
+			name:           "target and configuration",
+			targets:        []string{"Widget Extension"},
+			configurations: []string{"Release"},
+			selected:       []string{"Widget Extension/Release"},
+			changed:        []string{"CURRENT_PROJECT_VERSION = 43;", `MARKETING_VERSION = "1.3.0";`},
+		},
+		{
+			name:     "targets",
+			targets:  []string{"Widget Extension", "AppTests"},
+			selected: []string{"Widget Extension/Debug", "Widget Extension/Release", "AppTests/Debug", "AppTests/Release"},
+			changed: []string{
+				"CURRENT_PROJECT_VERSION = 43;",
+				"MARKETING_VERSION = 1.3.0;",
+				"CURRENT_PROJECT_VERSION = 43;",
+				`MARKETING_VERSION = "1.3.0";`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pbxproj, raw, err := loadPbxproj(t, test.targets, test.configurations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := pbxproj.Targets(), []string{"App", "Widget Extension", "AppTests"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Targets() = %q, want %q", got, want)
+			}
+			if got := pbxproj.Configurations(); !reflect.DeepEqual(got, test.selected) {
+				t.Errorf("Configurations() = %q, want %q", got, test.selected)
+			}
+			if pbxproj.Version() != "1.2.0" || pbxproj.BuildNumber() != "42" {
+				t.Fatalf("got %s (%s), want 1.2.0 (42)", pbxproj.Version(), pbxproj.BuildNumber())
+			}
+
+			pbxproj.SetVersion("1.3.0", "43")
+			files, err := pbxproj.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			updated := files["App.xcodeproj/project.pbxproj"]
+			if got := changedLines(t, raw, updated); !reflect.DeepEqual(got, test.changed) {
+				t.Errorf("changed lines = %q, want %q", got, test.changed)
+			}
+			// 1.2.0 and 42 become 1.3.0 and 43 by one byte each, so
+			// nothing but the values is rewritten.
+			if len(updated) != len(raw) {
+				t.Fatalf("size changed from %d to %d bytes", len(raw), len(updated))
+			}
+			diff := 0
+			for i := range raw {
+				if raw[i] != updated[i] {
+					diff++
+				}
+			}
+			if diff != len(test.changed) {
+				t.Errorf("%d bytes changed, want %d", diff, len(test.changed))
+			}
+		})
+	}
+}
+
+func TestPbxprojErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		targets        []string
+		configurations []string
+		want           string
+	}{
+		{
+			name:    "unknown target",
+			targets: []string{"Watch"},
+			want:    `target "Watch" is not found (available: App, Widget Extension, AppTests)`,
+		},
+		{
+			name:           "unknown configuration",
+			configurations: []string{"Staging"},
+			want:           `build configuration "Staging" is not found (available: Debug, Release)`,
+		},
+		{
+			name:    "only inherited values",
+			targets: []string{"App"},
+			want:    "CURRENT_PROJECT_VERSION is not found in build configurations: App/Debug, App/Release",
+		},
+		{
+			name:    "no values",
+			targets: []string{"AppTests"},
+			want:    "MARKETING_VERSION is not found in build configurations: AppTests/Debug, AppTests/Release",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := loadPbxproj(t, test.targets, test.configurations)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("NewPbxproj() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/blang/semver"
+)
+
+// finalChannel is the channel of versions without a pre-release part.
+const finalChannel = "final"
+
+// versionChannel returns the pre-release channel of a version, such as "beta"
+// for 2.0.0-beta.3, or finalChannel for 2.0.0.
+func versionChannel(versionString string) string {
+	version, err := semver.Make(versionString)
+	if err != nil || len(version.Pre) == 0 {
+		return finalChannel
+	}
+	return version.Pre[0].String()
+}
+
+func validateChannel(channel string) error {
+	if channel == finalChannel {
+		return nil
+	}
+	identifier, err := semver.NewPRVersion(channel)
+	if err != nil || identifier.IsNum {
+		return fmt.Errorf("invalid channel %q", channel)
+	}
+	return nil
+}
+
+// nextPrerelease returns the next pre-release of a version in channel. It
+// continues the pre-release of the same channel (2.0.0-beta.3 to
+// 2.0.0-beta.4), moves on to a later channel (2.0.0-beta.3 to 2.0.0-rc.1), or
+// starts the first pre-release of a final version (2.0.0 to 2.0.0-beta.1).
+func nextPrerelease(versionString string, channel string) (string, error) {
+	version, err := semver.Make(versionString)
+	if err != nil {
+		return versionString, err
+	}
+	version.Build = nil
+
+	if len(version.Pre) > 0 && version.Pre[0].String() == channel {
+		last := &version.Pre[len(version.Pre)-1]
+		if len(version.Pre) > 1 && last.IsNum {
+			last.VersionNum++
+		} else {
+			version.Pre = append(version.Pre, semver.PRVersion{VersionNum: 1, IsNum: true})
+		}
+		return version.String(), nil
+	}
+
+	next := version
+	next.Pre = []semver.PRVersion{{VersionStr: channel}, {VersionNum: 1, IsNum: true}}
+	if len(version.Pre) > 0 && next.LTE(version) {
+		return versionString, fmt.Errorf("%s does not follow %s", next, version)
+	}
+	return next.String(), nil
+}
+
+// promoteVersion returns the final version of a pre-release.
+func promoteVersion(versionString string) (string, error) {
+	version, err := semver.Make(versionString)
+	if err != nil {
+		return versionString, err
+	}
+	version.Pre = nil
+	version.Build = nil
+	return version.String(), nil
+}
+
+// nextVersions returns the versions that can follow the version of file.
+// A final version is followed by the next patch, minor and major versions and
+// their first pre-releases in channels. A pre-release is followed by the next
+// pre-release in its own or a later channel, and by its final version.
+func nextVersions(file VersionFile, channels []string) ([]string, error) {
+	current := file.Version()
+	channel := versionChannel(current)
+
+	var versions []string
+	add := func(version string) {
+		if version != current && !containsString(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+
+	if channel != finalChannel {
+		for _, c := range append([]string{channel}, channels...) {
+			if next, err := nextPrerelease(current, c); err == nil {
+				add(next)
+			}
+		}
+		final, err := promoteVersion(current)
+		if err != nil {
+			return nil, err
+		}
+		add(final)
+		return versions, nil
+	}
+
+	var bumps []string
+	for _, next := range []func() (string, error){file.NextPatch, file.NextMinor, file.NextMajor} {
+		version, err := next()
+		if err != nil {
+			return nil, err
+		}
+		bumps = append(bumps, version)
+		add(version)
+	}
+	for _, c := range channels {
+		for _, bump := range bumps {
+			// Versions such as 1.2 have no pre-releases.
+			if next, err := nextPrerelease(bump, c); err == nil {
+				add(next)
+			}
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testVersionFile returns a version file at version.
+func testVersionFile(t *testing.T, version string) VersionFile {
+	file, err := NewPubspec(VersionFileConfig{Path: "pubspec.yaml", VersionKey: pubspecVersionKey}, []byte("version: "+version+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestNextPrerelease(t *testing.T) {
+	tests := []struct {
+		version string
+		channel string
+		want    string
+		wantErr bool
+	}{
+		{version: "2.0.0", channel: "beta", want: "2.0.0-beta.1"},
+		{version: "2.0.0-beta.3", channel: "beta", want: "2.0.0-beta.4"},
+		{version: "2.0.0-beta", channel: "beta", want: "2.0.0-beta.1"},
+		{version: "2.0.0-beta.3", channel: "rc", want: "2.0.0-rc.1"},
+		{version: "2.0.0-beta.3+build.7", channel: "beta", want: "2.0.0-beta.4"},
+		{version: "2.0.0-alpha.2", channel: "beta", want: "2.0.0-beta.1"},
+		// Channels ordered below the current version can not follow it.
+		{version: "2.0.0-rc.1", channel: "beta", wantErr: true},
+		{version: "2.0.0-beta.3", channel: "alpha", wantErr: true},
+		{version: "1.2", channel: "beta", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := nextPrerelease(test.version, test.channel)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("nextPrerelease(%q, %q) = %q, want error", test.version, test.channel, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("nextPrerelease(%q, %q) = %q, %v, want %q", test.version, test.channel, got, err, test.want)
+		}
+	}
+}
+
+func TestVersionChannel(t *testing.T) {
+	tests := map[string]string{
+		"2.0.0":         finalChannel,
+		"2.0.0-beta.3":  "beta",
+		"2.0.0-rc":      "rc",
+		"2.0.0+build.1": finalChannel,
+		"1.2":           finalChannel,
+	}
+	for version, want := range tests {
+		if got := versionChannel(version); got != want {
+			t.Errorf("versionChannel(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestValidateChannel(t *testing.T) {
+	for _, channel := range []string{"alpha", "beta", "rc", finalChannel} {
+		if err := validateChannel(channel); err != nil {
+			t.Errorf("validateChannel(%q): %s", channel, err)
+		}
+	}
+	for _, channel := range []string{"", "1", "beta.1", "be ta"} {
+		if err := validateChannel(channel); err == nil {
+			t.Errorf("validateChannel(%q) succeeded", channel)
+		}
+	}
+}
+
+func TestNextVersions(t *testing.T) {
+	tests := []struct {
+		version  string
+		channels []string
+		want     []string
+	}{
+		{
+			version: "1.2.3",
+			want:    []string{"1.2.4", "1.3.0", "2.0.0"},
+		},
+		{
+			version:  "1.2.3",
+			channels: []string{"beta"},
+			want:     []string{"1.2.4", "1.3.0", "2.0.0", "1.2.4-beta.1", "1.3.0-beta.1", "2.0.0-beta.1"},
+		},
+		{
+			version:  "2.0.0-beta.3",
+			channels: []string{"alpha", "beta", "rc"},
+			want:     []string{"2.0.0-beta.4", "2.0.0-rc.1", "2.0.0"},
+		},
+		{
+			version:  "2.0.0-rc.2",
+			channels: []string{"beta", "rc"},
+			want:     []string{"2.0.0-rc.3", "2.0.0"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := nextVersions(testVersionFile(t, test.version), test.channels)
+		if err != nil {
+			t.Errorf("nextVersions(%q): %s", test.version, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("nextVersions(%q, %q) = %q, want %q", test.version, test.channels, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const pubspecVersionKey = "version"
+
+// pubspecVersionPattern matches a top-level `version: 1.2.3+45` line with an
+// optionally quoted value and a trailing comment.
+var pubspecVersionPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*):[ \t]*(?:"([^"]*)"|'([^']*)'|([^\s#"'][^#]*?))[ \t]*(?:#.*)?$`)
+
+// Pubspec is a version file that keeps the version in pubspec.yaml. The
+// `+build` suffix of the version is the build number. A version without the
+// suffix has no build number and is written back without one.
+type Pubspec struct {
+	path           string
+	raw            []byte
+	versionKey     string
+	value          textEdit
+	version        string
+	buildNumber    string
+	hasBuildNumber bool
+}
+
+func NewPubspec(config VersionFileConfig, raw []byte) (*Pubspec, error) {
+	pubspec := Pubspec{
+		path:       config.Path,
+		raw:        raw,
+		versionKey: config.VersionKey,
+	}
+
+	found := false
+	offset := 0
+	for _, line := range bytes.SplitAfter(raw, []byte("\n")) {
+		content := bytes.TrimRight(line, "\r\n")
+		m := pubspecVersionPattern.FindSubmatchIndex(content)
+		if m != nil && string(content[m[2]:m[3]]) == config.VersionKey {
+			for i := 4; i < len(m); i += 2 {
+				if m[i] >= 0 {
+					pubspec.value = textEdit{start: offset + m[i], end: offset + m[i+1], text: string(content[m[i]:m[i+1]])}
+				}
+			}
+			found = true
+			break
+		}
+		offset += len(line)
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not found", config.VersionKey)
+	}
+
+	pubspec.version = pubspec.value.text
+	if i := strings.Index(pubspec.value.text, "+"); i >= 0 {
+		pubspec.version = pubspec.value.text[:i]
+		pubspec.buildNumber = pubspec.value.text[i+1:]
+		pubspec.hasBuildNumber = true
+	}
+
+	return &pubspec, nil
+}
+
+func (pubspec *Pubspec) Version() string {
+	return pubspec.version
+}
+
+func (pubspec *Pubspec) BuildNumber() string {
+	return pubspec.buildNumber
+}
+
+func (pubspec *Pubspec) NextMajor() (string, error) {
+	return nextMajor(pubspec.Version())
+}
+
+func (pubspec *Pubspec) NextMinor() (string, error) {
+	return nextMinor(pubspec.Version())
+}
+
+func (pubspec *Pubspec) NextPatch() (string, error) {
+	return nextPatch(pubspec.Version())
+}
+
+func (pubspec *Pubspec) NextBuildNumber() (string, error) {
+	return nextBuildNumber(pubspec.BuildNumber())
+}
+
+func (pubspec *Pubspec) SetVersion(version string, build string) {
+	pubspec.version = version
+	if pubspec.hasBuildNumber {
+		pubspec.buildNumber = build
+	}
+}
+
+func (pubspec *Pubspec) Files() (map[string][]byte, error) {
+	edit := pubspec.value
+	edit.text = pubspec.version
+	if pubspec.hasBuildNumber {
+		edit.text += "+" + pubspec.buildNumber
+	}
+	return map[string][]byte{pubspec.path: applyTextEdits(pubspec.raw, []textEdit{edit})}, nil
+}
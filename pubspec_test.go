@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestPubspec(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		version     string
+		buildNumber string
+		want        string
+	}{
+		{
+			name:        "build suffix",
+			raw:         "name: app\n# The version of the app\nversion: 1.2.0+42 # bumped by deliverbot\n\nenvironment:\n  sdk: \">=2.12.0 <3.0.0\"\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "name: app\n# The version of the app\nversion: 1.3.0+43 # bumped by deliverbot\n\nenvironment:\n  sdk: \">=2.12.0 <3.0.0\"\n",
+		},
+		{
+			name:        "no build suffix",
+			raw:         "name: app\nversion: 1.2.0\n",
+			version:     "1.2.0",
+			buildNumber: "",
+			want:        "name: app\nversion: 1.3.0\n",
+		},
+		{
+			name:        "double quotes",
+			raw:         "version: \"1.2.0+42\"\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "version: \"1.3.0+43\"\n",
+		},
+		{
+			name:        "single quotes",
+			raw:         "version: '1.2.0+42'\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "version: '1.3.0+43'\n",
+		},
+		{
+			name:        "nested version keys are skipped",
+			raw:         "name: app\ndependencies:\n  plugin:\n    version: 0.1.0+1\nversion: 1.2.0+42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "name: app\ndependencies:\n  plugin:\n    version: 0.1.0+1\nversion: 1.3.0+43\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "pubspec.yaml", VersionKey: pubspecVersionKey}
+			pubspec, err := NewPubspec(config, []byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pubspec.Version() != test.version || pubspec.BuildNumber() != test.buildNumber {
+				t.Fatalf("got %s (%s), want %s (%s)", pubspec.Version(), pubspec.BuildNumber(), test.version, test.buildNumber)
+			}
+
+			pubspec.SetVersion("1.3.0", "43")
+			files, err := pubspec.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(files[config.Path]); got != test.want {
+				t.Errorf("got\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPubspecMissingVersion(t *testing.T) {
+	config := VersionFileConfig{Path: "pubspec.yaml", VersionKey: pubspecVersionKey}
+	for _, raw := range []string{
+		"name: app\n",
+		"name: app\n# version: 1.2.0+42\n",
+		"dependencies:\n  version: 1.2.0+42\n",
+	} {
+		if _, err := NewPubspec(config, []byte(raw)); err == nil {
+			t.Errorf("NewPubspec(%q) succeeded", raw)
+		}
+	}
+}
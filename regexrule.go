@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	regexRuleVersionGroup     = "version"
+	regexRuleBuildNumberGroup = "build"
+)
+
+var regexRuleTemplatePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z0-9_]+)\}|\$([A-Za-z0-9_]+)`)
+
+// RegexRule is a version file defined in config by a regular expression with
+// named `version` and `build` groups. When a replacement template is given,
+// the whole match is replaced with it; ${version}, ${build} and the other
+// named groups are expanded in the template. Otherwise only the groups are
+// replaced.
+type RegexRule struct {
+	path        string
+	raw         []byte
+	pattern     *regexp.Regexp
+	replacement string
+	match       []int
+	version     string
+	buildNumber string
+}
+
+// validateRegexRule reports errors in a rule before it is used.
+func validateRegexRule(config VersionFileConfig) error {
+	name := config.Name
+	if name == "" {
+		name = config.Path
+	}
+
+	pattern, err := regexp.Compile(config.Pattern)
+	if err != nil {
+		return fmt.Errorf("version file rule %q: invalid pattern: %s", name, err)
+	}
+	if pattern.SubexpIndex(regexRuleVersionGroup) < 0 {
+		return fmt.Errorf("version file rule %q: pattern has no (?P<%s>...) group", name, regexRuleVersionGroup)
+	}
+	for _, m := range regexRuleTemplatePattern.FindAllStringSubmatch(config.Replacement, -1) {
+		group := m[1] + m[2]
+		if group == "" || group == regexRuleVersionGroup || group == regexRuleBuildNumberGroup {
+			continue
+		}
+		if pattern.SubexpIndex(group) < 0 {
+			return fmt.Errorf("version file rule %q: replacement refers to unknown group %q", name, group)
+		}
+	}
+	return nil
+}
+
+func NewRegexRule(config VersionFileConfig, raw []byte) (*RegexRule, error) {
+	pattern, err := regexp.Compile(config.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	match := pattern.FindSubmatchIndex(raw)
+	if match == nil {
+		return nil, fmt.Errorf("pattern %s does not match", config.Pattern)
+	}
+
+	rule := RegexRule{
+		path:        config.Path,
+		raw:         raw,
+		pattern:     pattern,
+		replacement: config.Replacement,
+		match:       match,
+	}
+	rule.version = rule.group(regexRuleVersionGroup)
+	rule.buildNumber = rule.group(regexRuleBuildNumberGroup)
+	return &rule, nil
+}
+
+// group returns the text the named group matched.
+func (rule *RegexRule) group(name string) string {
+	i := rule.pattern.SubexpIndex(name)
+	if i < 0 || rule.match[2*i] < 0 {
+		return ""
+	}
+	return string(rule.raw[rule.match[2*i]:rule.match[2*i+1]])
+}
+
+func (rule *RegexRule) Version() string {
+	return rule.version
+}
+
+func (rule *RegexRule) BuildNumber() string {
+	return rule.buildNumber
+}
+
+func (rule *RegexRule) NextMajor() (string, error) {
+	return nextMajor(rule.Version())
+}
+
+func (rule *RegexRule) NextMinor() (string, error) {
+	return nextMinor(rule.Version())
+}
+
+func (rule *RegexRule) NextPatch() (string, error) {
+	return nextPatch(rule.Version())
+}
+
+func (rule *RegexRule) NextBuildNumber() (string, error) {
+	return nextBuildNumber(rule.BuildNumber())
+}
+
+func (rule *RegexRule) SetVersion(version string, build string) {
+	rule.version = version
+	rule.buildNumber = build
+}
+
+func (rule *RegexRule) Files() (map[string][]byte, error) {
+	values := map[string]string{
+		regexRuleVersionGroup:     rule.version,
+		regexRuleBuildNumberGroup: rule.buildNumber,
+	}
+
+	var edits []textEdit
+	if rule.replacement != "" {
+		groups := map[string]string{}
+		for _, name := range rule.pattern.SubexpNames() {
+			if name != "" {
+				groups[name] = rule.group(name)
+			}
+		}
+		for name, value := range values {
+			groups[name] = value
+		}
+		text := regexRuleTemplatePattern.ReplaceAllStringFunc(rule.replacement, func(s string) string {
+			if s == "$$" {
+				return "$"
+			}
+			return groups[strings.Trim(s, "${}")]
+		})
+		edits = append(edits, textEdit{start: rule.match[0], end: rule.match[1], text: text})
+	} else {
+		for name, value := range values {
+			i := rule.pattern.SubexpIndex(name)
+			if i < 0 || rule.match[2*i] < 0 {
+				continue
+			}
+			edits = append(edits, textEdit{start: rule.match[2*i], end: rule.match[2*i+1], text: value})
+		}
+	}
+
+	return map[string][]byte{rule.path: applyTextEdits(rule.raw, edits)}, nil
+}
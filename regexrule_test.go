@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestRegexRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		replacement string
+		raw         string
+		version     string
+		buildNumber string
+		want        string
+	}{
+		{
+			name:        "groups only",
+			pattern:     `VERSION = "(?P<version>[0-9.]+)"\nBUILD = (?P<build>[0-9]+)`,
+			raw:         "NAME = \"app\"\nVERSION = \"1.2.0\"\nBUILD = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "NAME = \"app\"\nVERSION = \"1.3.0\"\nBUILD = 43\n",
+		},
+		{
+			name:        "no build group",
+			pattern:     `version = '(?P<version>[^']+)'`,
+			raw:         "version = '1.2.0'\n",
+			version:     "1.2.0",
+			buildNumber: "",
+			want:        "version = '1.3.0'\n",
+		},
+		{
+			name:        "first match only",
+			pattern:     `v(?P<version>[0-9]+\.[0-9]+\.[0-9]+)`,
+			raw:         "v1.2.0\nv1.1.0\n",
+			version:     "1.2.0",
+			buildNumber: "",
+			want:        "v1.3.0\nv1.1.0\n",
+		},
+		{
+			name:        "replacement with other groups",
+			pattern:     `(?P<key>APP_VERSION)\s*:=\s*(?P<version>[0-9.]+)\s*\((?P<build>[0-9]+)\)`,
+			replacement: "${key} := ${version} (${build})",
+			raw:         "APP_VERSION:=1.2.0(42)\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION := 1.3.0 (43)\n",
+		},
+		{
+			name:        "replacement with short names and dollars",
+			pattern:     `price = (?P<version>[0-9.]+)`,
+			replacement: "price = $$$version",
+			raw:         "price = 1.2.0\n",
+			version:     "1.2.0",
+			buildNumber: "",
+			want:        "price = $1.3.0\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "VERSION", Pattern: test.pattern, Replacement: test.replacement}
+			if err := validateRegexRule(config); err != nil {
+				t.Fatal(err)
+			}
+			rule, err := NewRegexRule(config, []byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule.Version() != test.version || rule.BuildNumber() != test.buildNumber {
+				t.Fatalf("got %s (%s), want %s (%s)", rule.Version(), rule.BuildNumber(), test.version, test.buildNumber)
+			}
+
+			rule.SetVersion("1.3.0", "43")
+			files, err := rule.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(files[config.Path]); got != test.want {
+				t.Errorf("got\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateRegexRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		replacement string
+	}{
+		{name: "invalid pattern", pattern: `(?P<version>[0-9.]+`},
+		{name: "no version group", pattern: `(?P<build>[0-9]+)`},
+		{name: "unknown group in replacement", pattern: `(?P<version>[0-9.]+)`, replacement: "${name} ${version}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := VersionFileConfig{Path: "VERSION", Pattern: test.pattern, Replacement: test.replacement}
+			if err := validateRegexRule(config); err == nil {
+				t.Error("validateRegexRule succeeded")
+			}
+		})
+	}
+}
+
+func TestRegexRuleNoMatch(t *testing.T) {
+	config := VersionFileConfig{Path: "VERSION", Pattern: `VERSION = (?P<version>[0-9.]+)`}
+	if _, err := NewRegexRule(config, []byte("NAME = app\n")); err == nil {
+		t.Error("NewRegexRule succeeded")
+	}
+}
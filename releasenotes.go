@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	releaseNoteReplace = "replace"
+	releaseNotePrepend = "prepend"
+)
+
+// ReleaseNoteConfig is a file updated with the changelog in the release
+// commit, such as CHANGELOG.md or fastlane release notes.
+type ReleaseNoteConfig struct {
+	// Path may contain `*` to update every existing file that matches, e.g.
+	// fastlane/metadata/*/release_notes.txt for all languages.
+	Path string `toml:"path"`
+	// Mode is "replace" (default) to write the whole file, or "prepend" to
+	// insert the changelog above the previous releases.
+	Mode string `toml:"mode"`
+	// Template or TemplateFile is a text/template rendered with the
+	// Changelog. TemplateFile is a local path, not a file in the repository;
+	// it is read on every release, so it can be edited without restarting
+	// the bot.
+	Template     string `toml:"template"`
+	TemplateFile string `toml:"template_file"`
+}
+
+// releaseNotesTemplate is the default template of files other than Markdown.
+var releaseNotesTemplate = template.Must(template.New("release_notes").Parse(`{{if .Notes}}{{.Notes}}
+
+{{end}}{{range .Sections}}{{range .Entries}}- {{.Title}}
+{{end}}{{end}}`))
+
+// changelogHeadingPattern matches the heading of a release in a Markdown
+// changelog.
+var changelogHeadingPattern = regexp.MustCompile(`(?m)^## `)
+
+func (config ReleaseNoteConfig) template() (*template.Template, error) {
+	switch {
+	case config.Template != "":
+		return template.New(config.Path).Parse(config.Template)
+	case config.TemplateFile != "":
+		bytes, err := ioutil.ReadFile(config.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		return template.New(config.Path).Parse(string(bytes))
+	case strings.HasSuffix(config.Path, ".md"):
+		return changelogTemplate, nil
+	default:
+		return releaseNotesTemplate, nil
+	}
+}
+
+func (config ReleaseNoteConfig) validate() error {
+	if config.Path == "" {
+		return fmt.Errorf("release note path is required")
+	}
+	if config.Mode != releaseNoteReplace && config.Mode != releaseNotePrepend {
+		return fmt.Errorf("unknown release note mode %q for %s (available: %s, %s)", config.Mode, config.Path, releaseNoteReplace, releaseNotePrepend)
+	}
+	if config.Template != "" && config.TemplateFile != "" {
+		return fmt.Errorf("release note %s has both template and template_file", config.Path)
+	}
+	if _, err := config.template(); err != nil {
+		return fmt.Errorf("release note %s: %s", config.Path, err)
+	}
+	return nil
+}
+
+// releaseNoteFiles renders the release notes of the app, keyed by their paths
+// in the repository.
+func releaseNoteFiles(app *App, branch string, changelog *Changelog) (map[string][]byte, error) {
+	tree := repositoryTree{service: app.Service, branch: branch, dirs: map[string][]string{}}
+	files := map[string][]byte{}
+	for _, config := range app.ReleaseNotes {
+		t, err := config.template()
+		if err != nil {
+			return nil, fmt.Errorf("release note %s: %s", config.Path, err)
+		}
+		var rendered bytes.Buffer
+		if err := t.Execute(&rendered, changelog); err != nil {
+			return nil, fmt.Errorf("release note %s: %s", config.Path, err)
+		}
+
+		paths := []string{config.Path}
+		if strings.Contains(config.Path, "*") {
+			if paths, err = tree.glob(config.Path); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range paths {
+			if config.Mode == releaseNoteReplace {
+				files[p] = rendered.Bytes()
+				continue
+			}
+
+			var existing []byte
+			exists, err := tree.exists(p)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				if existing, err = app.Service.File(branch, p); err != nil {
+					return nil, err
+				}
+			}
+			files[p] = prependChangelog(existing, rendered.Bytes())
+		}
+	}
+	return files, nil
+}
+
+// prependChangelog inserts a section above the previous releases of a
+// changelog, keeping a title at the top of the file where it is.
+func prependChangelog(existing, section []byte) []byte {
+	if len(bytes.TrimSpace(existing)) == 0 {
+		return section
+	}
+
+	var buf bytes.Buffer
+	if loc := changelogHeadingPattern.FindIndex(existing); loc != nil {
+		buf.Write(existing[:loc[0]])
+		buf.Write(section)
+		if !bytes.HasSuffix(section, []byte("\n\n")) {
+			buf.WriteString("\n")
+		}
+		buf.Write(existing[loc[0]:])
+		return buf.Bytes()
+	}
+
+	buf.Write(existing)
+	if !bytes.HasSuffix(existing, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	buf.Write(section)
+	return buf.Bytes()
+}
+
+// repositoryTree looks up files in a branch, listing each directory once.
+type repositoryTree struct {
+	service *GitHubService
+	branch  string
+	// dirs holds the entries of the listed directories. Subdirectories end
+	// with "/".
+	dirs map[string][]string
+}
+
+func (tree *repositoryTree) list(dir string) ([]string, error) {
+	if names, ok := tree.dirs[dir]; ok {
+		return names, nil
+	}
+	p := dir
+	if p == "." {
+		p = ""
+	}
+	entries, err := tree.service.Directory(tree.branch, p)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.GetName()
+		if entry.GetType() == "dir" {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	tree.dirs[dir] = names
+	return names, nil
+}
+
+func (tree *repositoryTree) exists(p string) (bool, error) {
+	names, err := tree.list(path.Dir(p))
+	if err != nil {
+		return false, err
+	}
+	return containsString(names, path.Base(p)), nil
+}
+
+// glob returns the existing files that match a path with `*` wildcards.
+func (tree *repositoryTree) glob(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+	dirs := []string{"."}
+	for _, segment := range segments[:len(segments)-1] {
+		var next []string
+		for _, dir := range dirs {
+			names, err := tree.list(dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				if !strings.HasSuffix(name, "/") {
+					continue
+				}
+				if ok, _ := path.Match(segment, strings.TrimSuffix(name, "/")); ok {
+					next = append(next, path.Join(dir, name))
+				}
+			}
+		}
+		dirs = next
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		names, err := tree.list(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if ok, _ := path.Match(segments[len(segments)-1], name); ok {
+				paths = append(paths, path.Join(dir, name))
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPrependChangelog(t *testing.T) {
+	section := "## 1.3.0 (2018-06-13)\n\n### bug\n* Handle nil\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name: "empty",
+			want: section,
+		},
+		{
+			name:     "blank",
+			existing: "\n  \n",
+			want:     section,
+		},
+		{
+			name:     "title",
+			existing: "# Changelog\n\nAll notable changes.\n\n## 1.2.0 (2018-05-01)\n* Add arrays\n",
+			want:     "# Changelog\n\nAll notable changes.\n\n" + section + "\n## 1.2.0 (2018-05-01)\n* Add arrays\n",
+		},
+		{
+			name:     "no title",
+			existing: "## 1.2.0 (2018-05-01)\n* Add arrays\n",
+			want:     section + "\n## 1.2.0 (2018-05-01)\n* Add arrays\n",
+		},
+		{
+			// Headings of a lower level are not releases.
+			name:     "no releases",
+			existing: "# Changelog\n### Unreleased",
+			want:     "# Changelog\n### Unreleased\n\n" + section,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(prependChangelog([]byte(test.existing), []byte(section))); got != test.want {
+				t.Errorf("prependChangelog() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// testTreeService returns a GitHubService that lists the directories of the
+// files in main, and counts the listed directories.
+func testTreeService(t *testing.T, files []string, listed *int) *GitHubService {
+	return testGitHubService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("ref"); ref != "main" {
+			t.Errorf("ref = %q, want %q", ref, "main")
+		}
+		p := strings.TrimPrefix(r.URL.Path, "/repos/owner/repository/contents/")
+		var entries []map[string]string
+		seen := map[string]bool{}
+		for _, name := range files {
+			if p != "" {
+				if !strings.HasPrefix(name, p+"/") {
+					continue
+				}
+				name = strings.TrimPrefix(name, p+"/")
+			}
+			entry := map[string]string{"type": "file", "name": name}
+			if i := strings.Index(name, "/"); i >= 0 {
+				entry = map[string]string{"type": "dir", "name": name[:i]}
+			}
+			if !seen[entry["name"]] {
+				seen[entry["name"]] = true
+				entries = append(entries, entry)
+			}
+		}
+		if entries == nil {
+			http.NotFound(w, r)
+			return
+		}
+		*listed++
+		json.NewEncoder(w).Encode(entries)
+	}))
+}
+
+func TestRepositoryTreeGlob(t *testing.T) {
+	files := []string{
+		"CHANGELOG.md",
+		"fastlane/metadata/en-US/release_notes.txt",
+		"fastlane/metadata/ja/release_notes.txt",
+		"fastlane/metadata/ja/description.txt",
+		"fastlane/metadata/review_information/notes.txt",
+		"fastlane/screenshots/en-US/1.png",
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+		listed  int
+	}{
+		{pattern: "*.md", want: []string{"CHANGELOG.md"}, listed: 1},
+		{pattern: "fastlane/metadata/*/release_notes.txt", want: []string{"fastlane/metadata/en-US/release_notes.txt", "fastlane/metadata/ja/release_notes.txt"}, listed: 6},
+		{pattern: "fastlane/*/en-US/*", want: []string{"fastlane/metadata/en-US/release_notes.txt", "fastlane/screenshots/en-US/1.png"}, listed: 6},
+		{pattern: "fastlane/metadata/*/*.md", listed: 6},
+		// Directories that do not exist have no files.
+		{pattern: "fastlane/missing/*/release_notes.txt", listed: 2},
+		{pattern: "*/release_notes.txt", listed: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			listed := 0
+			tree := repositoryTree{service: testTreeService(t, files, &listed), branch: "main", dirs: map[string][]string{}}
+
+			got, err := tree.glob(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("glob(%q) = %q, want %q", test.pattern, got, test.want)
+			}
+			if listed != test.listed {
+				t.Errorf("%d directories listed, want %d", listed, test.listed)
+			}
+
+			// Directories are listed once.
+			if _, err := tree.glob(test.pattern); err != nil {
+				t.Fatal(err)
+			}
+			if listed != test.listed {
+				t.Errorf("%d directories listed after a second glob, want %d", listed, test.listed)
+			}
+		})
+	}
+}
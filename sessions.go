@@ -0,0 +1,282 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// sessionTTL is how long a release wizard can be left alone before it
+// expires.
+const sessionTTL = 24 * time.Hour
+
+// sessionPurgeInterval is how often the bolt session store looks for expired
+// sessions.
+const sessionPurgeInterval = time.Hour
+
+const (
+	defaultSessionStore = "memory"
+	defaultSessionPath  = "sessions.db"
+)
+
+// session is the state of a release wizard. Buttons and menus of the wizard
+// only carry the choice; the rest is kept here under the session ID.
+type session struct {
+	Parameters BuildParameters `json:"parameters"`
+	// Files are the version files fetched for the branch, so that the
+	// release is made from the version that was shown.
+	Files   fileSnapshot `json:"files"`
+	Expires time.Time    `json:"expires"`
+}
+
+type sessionStore interface {
+	// get returns nil if the session does not exist or has expired.
+	get(id string) (*session, error)
+	// put saves the session and extends its expiry.
+	put(id string, session *session) error
+	delete(id string) error
+	// take deletes the session and returns it, or nil if it does not exist
+	// or has expired. Of concurrent takes of a session, one returns it.
+	take(id string) (*session, error)
+}
+
+// sessionStores open a session store at the path.
+var sessionStores = map[string]func(path string) (sessionStore, error){
+	"memory": func(path string) (sessionStore, error) {
+		return newMemorySessionStore(), nil
+	},
+	"bolt": func(path string) (sessionStore, error) {
+		return openBoltSessionStore(path)
+	},
+}
+
+func sessionStoreNames() []string {
+	var names []string
+	for name := range sessionStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func openSessionStore(name, path string) (sessionStore, error) {
+	open, ok := sessionStores[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown session store %q (available: %s)", name, strings.Join(sessionStoreNames(), ", "))
+	}
+	return open(path)
+}
+
+// newSessionID returns a random ID that can not be guessed from other IDs.
+func newSessionID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %s", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+var sessionIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// memorySessionStore keeps sessions until the bot is restarted.
+type memorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]session
+	// now returns the current time the expiry of sessions is computed from.
+	now func() time.Time
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{sessions: map[string]session{}, now: time.Now}
+}
+
+func (store *memorySessionStore) get(id string) (*session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	s, ok := store.sessions[id]
+	if !ok || store.now().After(s.Expires) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (store *memorySessionStore) put(id string, s *session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	for k, v := range store.sessions {
+		if now.After(v.Expires) {
+			delete(store.sessions, k)
+		}
+	}
+	s.Expires = now.Add(sessionTTL)
+	store.sessions[id] = *s
+	return nil
+}
+
+func (store *memorySessionStore) delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.sessions, id)
+	return nil
+}
+
+func (store *memorySessionStore) take(id string) (*session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	s, ok := store.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(store.sessions, id)
+	if store.now().After(s.Expires) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// boltSessionStore keeps the sessions in a bolt database file, so that they
+// survive restarts. Sessions are saved as JSON under their IDs.
+type boltSessionStore struct {
+	db *bolt.DB
+	// now returns the current time the expiry of sessions is computed from.
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+var sessionsBucket = []byte("sessions")
+
+// openBoltSessionStore opens the database at the path, creating it if
+// needed. Another bot using the database makes it fail after a second.
+func openBoltSessionStore(path string) (*boltSessionStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %s", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open session database: %s", err)
+	}
+	return &boltSessionStore{db: db, now: time.Now}, nil
+}
+
+func (store *boltSessionStore) get(id string) (*session, error) {
+	var s *session
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		s, err = store.read(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %s", err)
+	}
+	return s, nil
+}
+
+// read returns the session, or nil if it does not exist or has expired.
+func (store *boltSessionStore) read(tx *bolt.Tx, id string) (*session, error) {
+	bytes := tx.Bucket(sessionsBucket).Get([]byte(id))
+	if bytes == nil {
+		return nil, nil
+	}
+	s := session{}
+	if err := json.Unmarshal(bytes, &s); err != nil {
+		return nil, err
+	}
+	if store.now().After(s.Expires) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (store *boltSessionStore) put(id string, s *session) error {
+	now := store.now()
+	s.Expires = now.Add(sessionTTL)
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %s", err)
+	}
+
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		if err := store.purge(tx, now); err != nil {
+			return err
+		}
+		return tx.Bucket(sessionsBucket).Put([]byte(id), bytes)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session: %s", err)
+	}
+	return nil
+}
+
+func (store *boltSessionStore) delete(id string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %s", err)
+	}
+	return nil
+}
+
+// take reads and deletes the session in one transaction. Bolt runs one
+// read-write transaction at a time.
+func (store *boltSessionStore) take(id string) (*session, error) {
+	var s *session
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if s, err = store.read(tx, id); err != nil {
+			return err
+		}
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to take session: %s", err)
+	}
+	return s, nil
+}
+
+// purge deletes the sessions that have expired, at most once in
+// sessionPurgeInterval.
+func (store *boltSessionStore) purge(tx *bolt.Tx, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.Sub(store.lastPurge) < sessionPurgeInterval {
+		return nil
+	}
+	store.lastPurge = now
+
+	var expired [][]byte
+	c := tx.Bucket(sessionsBucket).Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		s := session{}
+		if err := json.Unmarshal(v, &s); err != nil || now.After(s.Expires) {
+			expired = append(expired, k)
+		}
+	}
+	for _, k := range expired {
+		if err := tx.Bucket(sessionsBucket).Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const testSessionID = "0123456789abcdef0123456789abcdef"
+
+// testBoltSessionStore opens a bolt session store in a temporary directory.
+// The store is closed and removed at the end of the test.
+func testBoltSessionStore(t *testing.T) *boltSessionStore {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openBoltSessionStore(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.db.Close()
+		os.RemoveAll(dir)
+	})
+	return store
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	memoryStore := newMemorySessionStore()
+	boltStore := testBoltSessionStore(t)
+	stores := map[string]struct {
+		store sessionStore
+		now   *func() time.Time
+	}{
+		"memory": {store: memoryStore, now: &memoryStore.now},
+		"bolt":   {store: boltStore, now: &boltStore.now},
+	}
+
+	for name, test := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC)
+			*test.now = func() time.Time { return now }
+
+			if err := test.store.put(testSessionID, &session{Parameters: BuildParameters{App: "ios"}}); err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(sessionTTL)
+			if s, err := test.store.get(testSessionID); err != nil || s == nil || s.Parameters.App != "ios" {
+				t.Fatalf("get at the expiry = %v, %v, want the session", s, err)
+			}
+
+			// Saving the session extends its expiry.
+			s, _ := test.store.get(testSessionID)
+			if err := test.store.put(testSessionID, s); err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(sessionTTL)
+			if s, err := test.store.get(testSessionID); err != nil || s == nil {
+				t.Fatalf("get after put = %v, %v, want the session", s, err)
+			}
+
+			now = now.Add(time.Second)
+			if s, err := test.store.get(testSessionID); err != nil || s != nil {
+				t.Errorf("get after the expiry = %v, %v, want nil", s, err)
+			}
+			if s, err := test.store.take(testSessionID); err != nil || s != nil {
+				t.Errorf("take after the expiry = %v, %v, want nil", s, err)
+			}
+		})
+	}
+}
+
+func TestBoltSessionStorePurge(t *testing.T) {
+	store := testBoltSessionStore(t)
+	now := time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	count := func() int {
+		n := 0
+		store.db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket(sessionsBucket).Stats().KeyN
+			return nil
+		})
+		return n
+	}
+
+	put := func(id string) {
+		if err := store.put(id, &session{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := now
+
+	put("0000000000000000000000000000000a")
+	now = start.Add(30 * time.Minute)
+	put("0000000000000000000000000000000b")
+	now = start.Add(sessionTTL + time.Second)
+	put("0000000000000000000000000000000c")
+	if n := count(); n != 2 {
+		t.Errorf("%d sessions after a purge, want 2", n)
+	}
+
+	// Sessions that expire after a purge are kept until the next one.
+	now = start.Add(sessionTTL + 31*time.Minute)
+	put("0000000000000000000000000000000d")
+	if n := count(); n != 3 {
+		t.Errorf("%d sessions within the purge interval, want 3", n)
+	}
+	now = start.Add(sessionTTL + time.Hour + time.Second)
+	put("0000000000000000000000000000000e")
+	if n := count(); n != 3 {
+		t.Errorf("%d sessions after the next purge, want 3", n)
+	}
+}
+
+func TestBoltSessionStoreRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sessions.db")
+
+	store, err := openSessionStore("bolt", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{
+		Parameters: BuildParameters{App: "ios", Branch: "main", Version: "1.2.0"},
+		Files:      fileSnapshot{"Info.plist": []byte("<plist/>")},
+	}
+	if err := store.put(testSessionID, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.(*boltSessionStore).db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = openSessionStore("bolt", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.(*boltSessionStore).db.Close()
+	got, err := store.get(testSessionID)
+	if err != nil || got == nil {
+		t.Fatalf("get after restart = %v, %v, want the session", got, err)
+	}
+	if !reflect.DeepEqual(got.Parameters, s.Parameters) || string(got.Files["Info.plist"]) != "<plist/>" || !got.Expires.Equal(s.Expires) {
+		t.Errorf("get after restart = %+v, want %+v", got, s)
+	}
+	if s, err := store.get("fedcba9876543210fedcba9876543210"); err != nil || s != nil {
+		t.Errorf("get(unknown) = %v, %v, want nil", s, err)
+	}
+}
+
+func TestSessionStoreTake(t *testing.T) {
+	stores := map[string]sessionStore{
+		"memory": newMemorySessionStore(),
+		"bolt":   testBoltSessionStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.put(testSessionID, &session{Parameters: BuildParameters{App: "ios"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Buttons of two destinations clicked at the same time.
+			var wg sync.WaitGroup
+			taken := make(chan *session, 10)
+			for i := 0; i < cap(taken); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s, err := store.take(testSessionID)
+					if err != nil {
+						t.Error(err)
+					}
+					if s != nil {
+						taken <- s
+					}
+				}()
+			}
+			wg.Wait()
+			close(taken)
+
+			var apps []string
+			for s := range taken {
+				apps = append(apps, s.Parameters.App)
+			}
+			if len(apps) != 1 || apps[0] != "ios" {
+				t.Errorf("taken %q, want one ios session", apps)
+			}
+			if s, err := store.get(testSessionID); s != nil || err != nil {
+				t.Errorf("get after take = %v, %v, want nil", s, err)
+			}
+			if s, err := store.take("unknown"); s != nil || err != nil {
+				t.Errorf("take(unknown) = %v, %v, want nil", s, err)
+			}
+		})
+	}
+}
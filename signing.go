@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRequestAge is how old a signed request may be. Older requests are
+// rejected so that captured requests can not be replayed.
+const maxRequestAge = 5 * time.Minute
+
+// slackVerifier verifies that requests come from Slack, by the signing secret
+// or, for apps without one, by the legacy verification token.
+type slackVerifier struct {
+	signingSecret     string
+	verificationToken string
+	now               func() time.Time
+}
+
+// verifySignature checks the X-Slack-Signature header of a request. It
+// accepts any request if no signing secret is configured.
+func (v slackVerifier) verifySignature(header http.Header, body []byte) error {
+	if v.signingSecret == "" {
+		return nil
+	}
+
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp: %q", timestamp)
+	}
+	if age := v.now().Sub(time.Unix(seconds, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("request timestamp is too old: %s", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.signingSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+// verifyToken checks the verification token of a request. Requests are
+// verified by their signature instead when a signing secret is configured.
+func (v slackVerifier) verifyToken(token string) error {
+	if v.signingSecret != "" {
+		return nil
+	}
+	if !hmac.Equal([]byte(token), []byte(v.verificationToken)) {
+		return fmt.Errorf("invalid token: %s", token)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+var testRequestTime = time.Date(2018, 6, 13, 0, 0, 0, 0, time.UTC)
+
+// testSignature returns the signature Slack sends with a request.
+func testSignature(secret string, timestamp string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// testSignedHeader returns the headers of a request signed at testRequestTime.
+func testSignedHeader(body string) http.Header {
+	timestamp := strconv.FormatInt(testRequestTime.Unix(), 10)
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", testSignature(testSigningSecret, timestamp, body))
+	return header
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := "token=legacy&command=%2Fdeliver&text=help"
+	timestamp := strconv.FormatInt(testRequestTime.Unix(), 10)
+	signature := testSignature(testSigningSecret, timestamp, body)
+
+	tests := []struct {
+		name          string
+		signingSecret string
+		timestamp     string
+		signature     string
+		body          string
+		now           time.Time
+		ok            bool
+	}{
+		{name: "valid", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body, now: testRequestTime, ok: true},
+		{name: "within the window", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body, now: testRequestTime.Add(maxRequestAge), ok: true},
+		{name: "clock skew", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body, now: testRequestTime.Add(-maxRequestAge), ok: true},
+		{name: "tampered body", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body + "&user_id=U0", now: testRequestTime},
+		{name: "wrong secret", signingSecret: "other", timestamp: timestamp, signature: signature, body: body, now: testRequestTime},
+		{name: "wrong version", signingSecret: testSigningSecret, timestamp: timestamp, signature: "v1=" + signature[len("v0="):], body: body, now: testRequestTime},
+		{name: "no version", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature[len("v0="):], body: body, now: testRequestTime},
+		{name: "no signature", signingSecret: testSigningSecret, timestamp: timestamp, body: body, now: testRequestTime},
+		{name: "too old", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body, now: testRequestTime.Add(maxRequestAge + time.Second)},
+		{name: "too new", signingSecret: testSigningSecret, timestamp: timestamp, signature: signature, body: body, now: testRequestTime.Add(-maxRequestAge - time.Second)},
+		{name: "invalid timestamp", signingSecret: testSigningSecret, timestamp: "now", signature: testSignature(testSigningSecret, "now", body), body: body, now: testRequestTime},
+		// Without a signing secret, requests are verified by their token.
+		{name: "no signing secret", body: body, now: testRequestTime, ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := slackVerifier{signingSecret: test.signingSecret, now: func() time.Time { return test.now }}
+			header := http.Header{}
+			header.Set("X-Slack-Request-Timestamp", test.timestamp)
+			header.Set("X-Slack-Signature", test.signature)
+
+			err := verifier.verifySignature(header, []byte(test.body))
+			if (err == nil) != test.ok {
+				t.Errorf("verifySignature() = %v, want ok %v", err, test.ok)
+			}
+		})
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		signingSecret string
+		token         string
+		ok            bool
+	}{
+		{name: "valid", token: "legacy", ok: true},
+		{name: "invalid", token: "other"},
+		{name: "empty", token: ""},
+		// The token is not checked when requests are signed.
+		{name: "signed", signingSecret: testSigningSecret, token: "other", ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := slackVerifier{signingSecret: test.signingSecret, verificationToken: "legacy", now: time.Now}
+			err := verifier.verifyToken(test.token)
+			if (err == nil) != test.ok {
+				t.Errorf("verifyToken(%q) = %v, want ok %v", test.token, err, test.ok)
+			}
+		})
+	}
+}
@@ -4,27 +4,31 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/kishikawakatsumi/deliverbot/command"
 	"github.com/nlopes/slack"
 )
 
 const (
+	actionApp         = "app"
 	actionBranch      = "branch"
 	actionVersion     = "version"
 	actionBuildNumber = "buildNumber"
-	actionRelease     = "release"
-	actionExternal    = "external"
-	actionInternal    = "internal"
+	actionDestination = "destination"
+	actionOverride    = "override"
+	actionForm        = "form"
 	actionCancel      = "cancel"
 
-	callbackID  = "deliver"
-	helpMessage = "```\nUsage:\n\t@applebot\n\t@applebot ping\n\t@applebot help```"
+	callbackID = "deliver"
 )
 
 type SlackListener struct {
 	client         *slack.Client
+	api            slackAPI
+	sessions       sessionStore
 	botID          string
 	channelID      string
 	debugChannelID string
+	apps           Apps
 }
 
 func (s *SlackListener) ListenAndResponse() {
@@ -42,102 +46,146 @@ func (s *SlackListener) ListenAndResponse() {
 }
 
 func (s *SlackListener) handleMessageEvent(ev *slack.MessageEvent) error {
-	if ev.Channel != s.channelID && ev.Channel != s.debugChannelID {
+	if !s.listensTo(ev.Channel) || ev.User == s.botID || ev.BotID != "" || ev.SubType != "" {
 		return nil
 	}
 
+	// Commands are sent by mentioning the bot, or without the mention in a
+	// direct message.
 	fields := strings.Fields(ev.Msg.Text)
-	if len(fields) == 0 || len(fields) > 2 {
+	if len(fields) > 0 && fields[0] == fmt.Sprintf("<@%s>", s.botID) {
+		fields = fields[1:]
+	} else if !isDirectMessage(ev.Channel) {
 		return nil
 	}
-
-	mentionToBot := fields[0] == fmt.Sprintf("<@%s>", s.botID)
-	if len(fields) == 1 && mentionToBot {
-		return s.deliver(ev)
-	}
-	if len(fields) == 2 && mentionToBot && fields[1] == "ping" {
-		err := s.respond(ev.Channel, "pong")
-		return err
+	if len(fields) == 0 {
+		fields = []string{"deliver"}
 	}
-	if len(fields) == 2 && mentionToBot && fields[1] == "help" {
-		err := s.respond(ev.Channel, helpMessage)
-		return err
+
+	return command.Run(s, ev.Channel, ev.User, fields[0], fields[1:])
+}
+
+// isDirectMessage reports whether the channel is a direct message with the
+// bot.
+func isDirectMessage(channel string) bool {
+	return strings.HasPrefix(channel, "D")
+}
+
+func (s *SlackListener) listensTo(channel string) bool {
+	if channel == s.channelID || channel == s.debugChannelID || isDirectMessage(channel) {
+		return true
 	}
-	if len(fields) == 2 && mentionToBot && fields[1] == "deliver" {
-		return s.deliver(ev)
+	for _, id := range s.apps.ChannelIDs() {
+		if id == channel {
+			return true
+		}
 	}
+	return false
+}
 
-	return nil
+// channelApps returns the apps that can be delivered from the channel.
+// Every app can be delivered from the debug channel.
+func (s *SlackListener) channelApps(channel string) Apps {
+	if channel == s.debugChannelID {
+		return s.apps
+	}
+	return s.apps.ForChannel(channel)
 }
 
-func (s *SlackListener) deliver(ev *slack.MessageEvent) error {
-	buildParameters := BuildParameters{}
+func (s *SlackListener) deliver(channel string, appName string) error {
+	apps := s.channelApps(channel)
+
+	if appName != "" {
+		app := apps.Find(appName)
+		if app == nil {
+			return s.respond(channel, unknownAppMessage(appName, apps))
+		}
+		apps = Apps{app}
+	}
+
+	if len(apps) == 0 {
+		return s.respond(channel, "No app can be delivered from this channel.")
+	}
 
-	actions, err := branchOptions(buildParameters)
+	sessionID, err := newSessionID()
 	if err != nil {
 		return err
 	}
-	messageParameters := slack.PostMessageParameters{
-		Attachments: []slack.Attachment{
-			{
-				Text:       "Branch:",
-				CallbackID: callbackID,
-				Actions:    actions,
-			},
-		},
+	session := &session{}
+
+	var text string
+	var actions []blockElement
+	if len(apps) == 1 {
+		session.Parameters = BuildParameters{App: apps[0].Name}
+
+		actions, err = branchOptions(apps[0], sessionID)
+		if err != nil {
+			return err
+		}
+		text = fmt.Sprintf("App: `%s` ✔︎\nBranch:", apps[0].Name)
+	} else {
+		actions = appOptions(apps, sessionID)
+		text = "App:"
+	}
+	if err := s.sessions.put(sessionID, session); err != nil {
+		return fmt.Errorf("failed to save session: %s", err)
 	}
 
-	if _, _, err := s.client.PostMessage(ev.Channel, "", messageParameters); err != nil {
+	blocks := []block{
+		sectionBlock(text),
+		actionsBlock(callbackID, actions),
+	}
+	if err := s.api.postMessage(channel, text, blocks); err != nil {
 		return fmt.Errorf("failed to post message: %s", err)
 	}
 	return nil
 }
 
 func (s *SlackListener) respond(channel string, text string) error {
-	_, _, err := s.client.PostMessage(channel, text, slack.NewPostMessageParameters())
-	return fmt.Errorf("failed to post message: %s", err)
+	if _, _, err := s.client.PostMessage(channel, text, slack.NewPostMessageParameters()); err != nil {
+		return fmt.Errorf("failed to post message: %s", err)
+	}
+	return nil
 }
 
-func branchOptions(parameters BuildParameters) ([]slack.AttachmentAction, error) {
-	defaultBranch, err := service.DefaultBranch()
+func appOptions(apps Apps, sessionID string) []blockElement {
+	var options []optionObject
+	for _, app := range apps {
+		options = append(options, option(app.Name, app.Name))
+	}
+
+	actions := []blockElement{
+		selectElement(actionApp, "Select app...", options),
+		cancelAction(sessionID),
+	}
+	return actions
+}
+
+func branchOptions(app *App, sessionID string) ([]blockElement, error) {
+	defaultBranch, err := app.Service.DefaultBranch()
 	if err != nil {
-		return []slack.AttachmentAction{}, err
+		return []blockElement{}, err
 	}
 
-	var options []slack.AttachmentActionOption
-	branches, err := service.Branches()
+	var options []optionObject
+	branches, err := app.Service.Branches()
 	if err != nil {
-		return []slack.AttachmentAction{}, err
+		return []blockElement{}, err
 	}
 	for _, branch := range branches {
 		if branch.GetName() == *defaultBranch {
 			continue
 		}
-		featureBranchParameters := parameters
-		featureBranchParameters.Branch = branch.GetName()
-		options = append(options, slack.AttachmentActionOption{
-			Text:  branch.GetName(),
-			Value: featureBranchParameters.string(),
-		})
-	}
-
-	defaultBranchParameters := parameters
-	defaultBranchParameters.Branch = *defaultBranch
-	actions := []slack.AttachmentAction{
-		{
-			Name:  actionBranch,
-			Text:  defaultBranchParameters.Branch,
-			Value: defaultBranchParameters.string(),
-			Type:  "button",
-			Style: "primary",
-		},
-		{
-			Name:    actionBranch,
-			Text:    "Other branch...",
-			Type:    "select",
-			Options: options,
-		},
-		cancelAction(),
+		options = append(options, option(branch.GetName(), branch.GetName()))
+	}
+
+	actions := []blockElement{
+		buttonElement(actionBranch, *defaultBranch, *defaultBranch, "primary"),
+	}
+	if len(options) > 0 {
+		actions = append(actions, selectElement(actionBranch, "Other branch...", options))
 	}
+	actions = append(actions, buttonElement(actionForm, "Release form...", "", ""))
+	actions = append(actions, cancelAction(sessionID))
 	return actions, nil
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const slackAPIURL = "https://slack.com/api/"
+
+// slackAPI calls the Slack Web API methods that the slack package does not
+// support, such as posting Block Kit messages.
+type slackAPI struct {
+	token string
+	// url is the base URL of the methods, slackAPIURL if empty.
+	url string
+}
+
+type slackAPIResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// slackAPIError is the error code of a response that is not ok, such as
+// "hash_conflict".
+type slackAPIError struct {
+	method string
+	code   string
+}
+
+func (err slackAPIError) Error() string {
+	return fmt.Sprintf("failed to call %s: %s", err.method, err.code)
+}
+
+// call calls the method and decodes the response into result unless it is
+// nil.
+func (api slackAPI) call(method string, request interface{}, result interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	url := api.url
+	if url == "" {
+		url = slackAPIURL
+	}
+	req, err := http.NewRequest(http.MethodPost, url+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+api.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %s", method, err)
+	}
+	defer resp.Body.Close()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %s", method, err)
+	}
+	var response slackAPIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to decode %s response: %s", method, err)
+	}
+	if !response.OK {
+		return slackAPIError{method: method, code: response.Error}
+	}
+	if result != nil {
+		return json.Unmarshal(body, result)
+	}
+	return nil
+}
+
+// blockMessage is a message with blocks. Text is shown in notifications.
+type blockMessage struct {
+	Channel         string  `json:"channel,omitempty"`
+	Text            string  `json:"text"`
+	Blocks          []block `json:"blocks,omitempty"`
+	ReplaceOriginal bool    `json:"replace_original,omitempty"`
+}
+
+func (api slackAPI) postMessage(channel, text string, blocks []block) error {
+	return api.call("chat.postMessage", blockMessage{Channel: channel, Text: text, Blocks: blocks}, nil)
+}
+
+type viewResponse struct {
+	View struct {
+		ID string `json:"id"`
+	} `json:"view"`
+}
+
+// openView opens a modal and returns its ID. The trigger ID of an
+// interaction expires in 3 seconds.
+func (api slackAPI) openView(triggerID string, v view) (string, error) {
+	var response viewResponse
+	request := map[string]interface{}{"trigger_id": triggerID, "view": v}
+	if err := api.call("views.open", request, &response); err != nil {
+		return "", err
+	}
+	return response.View.ID, nil
+}
+
+// updateView replaces a view. With a hash, the view is only replaced if it has
+// not changed since the hash was sent to the app.
+func (api slackAPI) updateView(viewID string, hash string, v view) error {
+	request := map[string]interface{}{"view_id": viewID, "view": v}
+	if hash != "" {
+		request["hash"] = hash
+	}
+	return api.call("views.update", request, nil)
+}
+
+// respond replaces the message an interaction came from through the
+// response_url of the interaction.
+func respond(responseURL string, text string, blocks []block) error {
+	body, err := json.Marshal(blockMessage{Text: text, Blocks: blocks, ReplaceOriginal: true})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to respond: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to respond: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackAPICall(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		viewID   string
+		err      string
+	}{
+		{name: "ok", status: http.StatusOK, response: `{"ok": true, "view": {"id": "V123"}}`, viewID: "V123"},
+		{name: "error", status: http.StatusOK, response: `{"ok": false, "error": "expired_trigger_id"}`, err: "failed to call views.open: expired_trigger_id"},
+		{name: "error with warnings", status: http.StatusOK, response: `{"ok": false, "error": "invalid_arguments", "response_metadata": {"messages": ["[ERROR] missing required field: title"]}}`, err: "failed to call views.open: invalid_arguments"},
+		{name: "not JSON", status: http.StatusServiceUnavailable, response: `Service Unavailable`, err: "failed to decode views.open response: invalid character 'S' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/views.open" {
+					t.Errorf("path = %q, want %q", r.URL.Path, "/views.open")
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer xoxb-token" {
+					t.Errorf("Authorization = %q, want %q", got, "Bearer xoxb-token")
+				}
+				var request map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request["trigger_id"] != "T1" {
+					t.Errorf("request = %v, %v, want trigger_id T1", request, err)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.response))
+			}))
+			defer server.Close()
+			api := slackAPI{token: "xoxb-token", url: server.URL + "/"}
+
+			viewID, err := api.openView("T1", view{})
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if viewID != test.viewID || got != test.err {
+				t.Errorf("openView() = %q, %q, want %q, %q", viewID, got, test.viewID, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+const slashHelpMessage = "```\nUsage:\n\t/deliver [app [branch [version [build number [destination]]]]]\n\t/deliver form [app]\n\t/deliver help```"
+
+// releaseArguments is the number of arguments of /deliver that release
+// without asking anything.
+const releaseArguments = 5
+
+// slashCommandHandler receives the /deliver slash command.
+type slashCommandHandler struct {
+	verifier    slackVerifier
+	listener    *SlackListener
+	interaction interactionHandler
+}
+
+func (h slashCommandHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sugar.Errorf("Invalid method: %s", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sugar.Errorf("Failed to read request body: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := h.verifier.verifySignature(r.Header, body); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	command, err := slack.SlashCommandParse(r)
+	if err != nil {
+		sugar.Errorf("Failed to parse slash command: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := h.verifier.verifyToken(command.Token); err != nil {
+		sugar.Errorf("Failed to verify request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !h.listener.listensTo(command.ChannelID) {
+		respondCommand(w, "ephemeral", "No app can be delivered from this channel.")
+		return
+	}
+	apps := h.listener.channelApps(command.ChannelID)
+
+	args := strings.Fields(command.Text)
+	switch {
+	case len(args) > 0 && args[0] == "help":
+		respondCommand(w, "ephemeral", slashHelpMessage)
+	case len(args) > 0 && args[0] == "form":
+		if len(args) > 2 {
+			respondCommand(w, "ephemeral", slashHelpMessage)
+			return
+		}
+		if len(args) == 2 {
+			app := apps.Find(args[1])
+			if app == nil {
+				respondCommand(w, "ephemeral", unknownAppMessage(args[1], apps))
+				return
+			}
+			apps = Apps{app}
+		}
+		if len(apps) != 1 {
+			respondCommand(w, "ephemeral", fmt.Sprintf("Choose an app: `/deliver form [%s]`", strings.Join(apps.Names(), "|")))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		go h.interaction.openReleaseForm(command.TriggerID, apps[0], command.ChannelID)
+	case len(args) <= 1:
+		// Ask for the rest in the release wizard.
+		var appName string
+		if len(args) > 0 {
+			appName = args[0]
+		}
+		w.WriteHeader(http.StatusOK)
+		go func() {
+			if err := h.listener.deliver(command.ChannelID, appName); err != nil {
+				sugar.Errorf("Failed to start the release wizard: %s", err)
+				respond(command.ResponseURL, fmt.Sprintf("%s", err), nil)
+			}
+		}()
+	case len(args) < releaseArguments:
+		// Start the release wizard at the first argument that is missing.
+		app := apps.Find(args[0])
+		if app == nil {
+			respondCommand(w, "ephemeral", unknownAppMessage(args[0], apps))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		go h.startWizard(command, app, args[1:])
+	case len(args) == releaseArguments:
+		app := apps.Find(args[0])
+		if app == nil {
+			respondCommand(w, "ephemeral", unknownAppMessage(args[0], apps))
+			return
+		}
+		parameters := BuildParameters{
+			App:         app.Name,
+			Branch:      args[1],
+			Version:     args[2],
+			BuildNumber: args[3],
+			Destination: args[4],
+		}
+		respondCommand(w, "in_channel", fmt.Sprintf("Checking %s `%s (%s)` on `%s` ...", app.Name, parameters.Version, parameters.BuildNumber, parameters.Branch))
+		go h.release(command, app, parameters)
+	default:
+		respondCommand(w, "ephemeral", slashHelpMessage)
+	}
+}
+
+// release checks the release given in the arguments and releases it.
+func (h slashCommandHandler) release(command slack.SlashCommand, app *App, parameters BuildParameters) {
+	failed := fmt.Sprintf(":x: %s `%s (%s)` can not be released.", app.Name, parameters.Version, parameters.BuildNumber)
+
+	destination, errors := validateRelease(app, parameters)
+	if len(errors) > 0 {
+		var lines []string
+		for _, field := range releaseFields {
+			if message, ok := errors[field]; ok {
+				lines = append(lines, fmt.Sprintf("%s: %s", field, message))
+			}
+		}
+		if err := respond(command.ResponseURL, failed, []block{sectionBlock(failed), contextBlock(strings.Join(lines, "\n"))}); err != nil {
+			sugar.Error(err)
+		}
+		return
+	}
+	versionFile, err := loadRelease(app, parameters)
+	if err != nil {
+		if err := respond(command.ResponseURL, failed, []block{sectionBlock(failed), contextBlock(fmt.Sprintf("%s", err))}); err != nil {
+			sugar.Error(err)
+		}
+		return
+	}
+
+	text := fmt.Sprintf("Releasing %s `%s (%s)` to %s ...", app.Name, parameters.Version, parameters.BuildNumber, destination.Description)
+	if err := respond(command.ResponseURL, text, []block{sectionBlock(text)}); err != nil {
+		sugar.Error(err)
+	}
+	h.interaction.release(command.ChannelID, app, destination, parameters, versionFile)
+}
+
+// wizardSteps are the steps of the release wizard the arguments after the app
+// answer, in order.
+var wizardSteps = []string{actionBranch, actionVersion, actionBuildNumber}
+
+// startWizard posts the release wizard with the steps the arguments answer
+// already taken. The arguments are checked as if they were chosen in the
+// wizard.
+func (h slashCommandHandler) startWizard(command slack.SlashCommand, app *App, args []string) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		sugar.Error(err)
+		respond(command.ResponseURL, fmt.Sprintf("%s", err), nil)
+		return
+	}
+	s := &session{Parameters: BuildParameters{App: app.Name}}
+
+	var text string
+	var actions []blockElement
+	for i, value := range args {
+		step := wizardSteps[i]
+		text, actions, err = h.interaction.advance(app, sessionID, s, step, s.Parameters.with(step, value))
+		if err != nil {
+			failed := fmt.Sprintf(":x: The release wizard can not be started at `%s`.", value)
+			if err := respond(command.ResponseURL, failed, []block{sectionBlock(failed), contextBlock(fmt.Sprintf("%s", err))}); err != nil {
+				sugar.Error(err)
+			}
+			return
+		}
+	}
+
+	blocks := []block{
+		sectionBlock(text),
+		actionsBlock(callbackID, actions),
+	}
+	if err := h.interaction.api.postMessage(command.ChannelID, text, blocks); err != nil {
+		sugar.Errorf("Failed to start the release wizard: %s", err)
+		respond(command.ResponseURL, fmt.Sprintf("failed to post message: %s", err), nil)
+	}
+}
+
+func unknownAppMessage(name string, apps Apps) string {
+	return fmt.Sprintf("Unknown app `%s`. Available apps: `%s`", name, strings.Join(apps.Names(), "`, `"))
+}
+
+// respondCommand responds to a slash command. An ephemeral response is only
+// shown to the user.
+func respondCommand(w http.ResponseWriter, responseType, text string) {
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": responseType,
+		"text":          text,
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slackRequest is a request sent to the fake Slack server: a Web API method
+// or the response URL of a command.
+type slackRequest struct {
+	path string
+	body string
+}
+
+func TestSlashCommandHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// response is the text of the response to the command.
+		response     string
+		responseType string
+		// request is sent to Slack after the response.
+		request slackRequest
+	}{
+		{name: "help", text: "help", responseType: "ephemeral", response: slashHelpMessage},
+		{name: "form", text: "form ios", request: slackRequest{path: "/api/views.open", body: `"trigger_id":"T1"`}},
+		{name: "form with unknown app", text: "form web", responseType: "ephemeral", response: "Unknown app `web`. Available apps: `ios`"},
+		{name: "form with too many arguments", text: "form ios main", responseType: "ephemeral", response: slashHelpMessage},
+		// The wizard checks the branch before it is posted.
+		{name: "wizard", text: "ios main", request: slackRequest{path: "/response", body: ":x: The release wizard can not be started at `main`."}},
+		{name: "wizard with unknown app", text: "web main", responseType: "ephemeral", response: "Unknown app `web`. Available apps: `ios`"},
+		{name: "release", text: "ios main 1.2.0 42 nowhere", responseType: "in_channel", response: "Checking ios `1.2.0 (42)` on `main` ...", request: slackRequest{path: "/response", body: ":x: ios `1.2.0 (42)` can not be released."}},
+		{name: "release with unknown app", text: "web main 1.2.0 42 release", responseType: "ephemeral", response: "Unknown app `web`. Available apps: `ios`"},
+		{name: "too many arguments", text: "ios main 1.2.0 42 release now", responseType: "ephemeral", response: slashHelpMessage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := make(chan slackRequest, 10)
+			slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				requests <- slackRequest{path: r.URL.Path, body: string(body)}
+				w.Write([]byte(`{"ok": true, "view": {"id": "V1"}}`))
+			}))
+			defer slackServer.Close()
+
+			app := &App{Name: "ios", VersioningScheme: "semver", ChannelIDs: []string{"C1"}, Destinations: defaultDestinations, Service: testGitHubService(t, http.NotFoundHandler())}
+			api := slackAPI{url: slackServer.URL + "/api/"}
+			h := slashCommandHandler{
+				verifier: slackVerifier{verificationToken: "legacy", now: time.Now},
+				listener: &SlackListener{api: api, sessions: newMemorySessionStore(), apps: Apps{app}},
+				interaction: interactionHandler{
+					api:      api,
+					sessions: newMemorySessionStore(),
+					apps:     Apps{app},
+					now:      time.Now,
+				},
+			}
+			form := url.Values{
+				"token":        {"legacy"},
+				"command":      {"/deliver"},
+				"text":         {test.text},
+				"channel_id":   {"C1"},
+				"trigger_id":   {"T1"},
+				"response_url": {slackServer.URL + "/response"},
+			}
+			r := httptest.NewRequest(http.MethodPost, "/slash", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			var response struct {
+				ResponseType string `json:"response_type"`
+				Text         string `json:"text"`
+			}
+			if w.Body.Len() > 0 {
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if response.ResponseType != test.responseType || response.Text != test.response {
+				t.Errorf("response = %s %q, want %s %q", response.ResponseType, response.Text, test.responseType, test.response)
+			}
+
+			if test.request.path == "" {
+				return
+			}
+			select {
+			case request := <-requests:
+				if request.path != test.request.path || !strings.Contains(request.body, test.request.body) {
+					t.Errorf("request = %s %s, want %s with %s", request.path, request.body, test.request.path, test.request.body)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("no request sent to %s", test.request.path)
+			}
+		})
+	}
+}
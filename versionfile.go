@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+)
+
+// VersionFile is a file in the repository that holds the app version and
+// build number.
+type VersionFile interface {
+	Version() string
+	BuildNumber() string
+
+	NextMajor() (string, error)
+	NextMinor() (string, error)
+	NextPatch() (string, error)
+	NextBuildNumber() (string, error)
+
+	SetVersion(version string, buildNumber string)
+	// Files renders the updated files keyed by their paths in the repository.
+	Files() (map[string][]byte, error)
+}
+
+type VersionFileConfig struct {
+	Name           string `toml:"name"`
+	Type           string `toml:"type"`
+	Path           string `toml:"path"`
+	VersionKey     string `toml:"version_key"`
+	BuildNumberKey string `toml:"build_number_key"`
+	// Xcconfig is the path of the xcconfig file that defines the build settings
+	// referenced from Info.plist such as $(MARKETING_VERSION).
+	Xcconfig string `toml:"xcconfig"`
+	// Targets and Configurations limit the build configurations updated in
+	// project.pbxproj. All of them are updated by default.
+	Targets        []string `toml:"targets"`
+	Configurations []string `toml:"configurations"`
+	// Flavor is the product flavor in build.gradle that holds the version.
+	// defaultConfig is used by default.
+	Flavor string `toml:"flavor"`
+	// Pattern and Replacement define a "regex" version file rule.
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+}
+
+// fetchFunc returns the contents of a file in the repository.
+type fetchFunc func(path string) ([]byte, error)
+
+type versionFileType struct {
+	versionKey     string
+	buildNumberKey string
+	load           func(config VersionFileConfig, bytes []byte, fetch fetchFunc) (VersionFile, error)
+}
+
+var versionFileTypes = map[string]versionFileType{
+	"infoplist": {
+		versionKey:     infoPlistVersionKey,
+		buildNumberKey: infoPlistBuildNumberKey,
+		load: func(config VersionFileConfig, bytes []byte, fetch fetchFunc) (VersionFile, error) {
+			return NewInfoPlist(config, bytes, fetch)
+		},
+	},
+	"xcconfig": {
+		versionKey:     xcconfigVersionKey,
+		buildNumberKey: xcconfigBuildNumberKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewXcconfig(config, bytes)
+		},
+	},
+	"pbxproj": {
+		versionKey:     pbxprojVersionKey,
+		buildNumberKey: pbxprojBuildNumberKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewPbxproj(config, bytes)
+		},
+	},
+	"gradle": {
+		versionKey:     gradleVersionKey,
+		buildNumberKey: gradleBuildNumberKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewGradle(config, bytes)
+		},
+	},
+	"gradle_properties": {
+		versionKey:     gradleVersionKey,
+		buildNumberKey: gradleBuildNumberKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewGradleProperties(config, bytes)
+		},
+	},
+	"pubspec": {
+		versionKey: pubspecVersionKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewPubspec(config, bytes)
+		},
+	},
+	"package_json": {
+		versionKey: packageJSONVersionKey,
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewPackageJSON(config, bytes)
+		},
+	},
+	"regex": {
+		load: func(config VersionFileConfig, bytes []byte, _ fetchFunc) (VersionFile, error) {
+			return NewRegexRule(config, bytes)
+		},
+	},
+}
+
+func versionFileTypeNames() []string {
+	var names []string
+	for name := range versionFileTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withDefaults fills in the keys the version file type uses by default.
+func (config VersionFileConfig) withDefaults() VersionFileConfig {
+	fileType, ok := versionFileTypes[config.Type]
+	if !ok {
+		return config
+	}
+	if config.VersionKey == "" {
+		config.VersionKey = fileType.versionKey
+	}
+	if config.BuildNumberKey == "" {
+		config.BuildNumberKey = fileType.buildNumberKey
+	}
+	return config
+}
+
+func (config VersionFileConfig) validate() error {
+	if config.Path == "" {
+		return fmt.Errorf("version file path is required")
+	}
+	if _, ok := versionFileTypes[config.Type]; !ok {
+		return fmt.Errorf("unknown version file type %q for %s (available: %v)", config.Type, config.Path, versionFileTypeNames())
+	}
+	if config.Xcconfig != "" && config.Type != "infoplist" {
+		return fmt.Errorf("xcconfig is only supported by infoplist version files: %s", config.Path)
+	}
+	if (len(config.Targets) > 0 || len(config.Configurations) > 0) && config.Type != "pbxproj" {
+		return fmt.Errorf("targets and configurations are only supported by pbxproj version files: %s", config.Path)
+	}
+	if config.Flavor != "" && config.Type != "gradle" {
+		return fmt.Errorf("flavor is only supported by gradle version files: %s", config.Path)
+	}
+	if config.Type == "regex" {
+		return validateRegexRule(config)
+	}
+	if config.Pattern != "" || config.Replacement != "" {
+		return fmt.Errorf("pattern and replacement are only supported by regex version files: %s", config.Path)
+	}
+	return nil
+}
+
+// LoadVersionFile reads the version file described by config.
+func LoadVersionFile(config VersionFileConfig, fetch fetchFunc) (VersionFile, error) {
+	fileType, ok := versionFileTypes[config.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown version file type: %s", config.Type)
+	}
+
+	bytes, err := fetch(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fileType.load(config.withDefaults(), bytes, fetch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", config.Path, err)
+	}
+	return file, nil
+}
+
+// fileSnapshot holds the files fetched while loading a version file, so that
+// the file can be loaded again later without fetching it from the repository.
+type fileSnapshot map[string][]byte
+
+// record returns a fetchFunc that stores the fetched files in the snapshot.
+func (snapshot fileSnapshot) record(fetch fetchFunc) fetchFunc {
+	return func(path string) ([]byte, error) {
+		bytes, err := fetch(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[path] = bytes
+		return bytes, nil
+	}
+}
+
+func (snapshot fileSnapshot) fetch(path string) ([]byte, error) {
+	bytes, ok := snapshot[path]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", path)
+	}
+	return bytes, nil
+}
+
+func nextMajor(versionString string) (string, error) {
+	return nextVersion(versionString, 0)
+}
+
+func nextMinor(versionString string) (string, error) {
+	return nextVersion(versionString, 1)
+}
+
+func nextPatch(versionString string) (string, error) {
+	return nextVersion(versionString, 2)
+}
+
+// nextVersion bumps the component at index of a marketing version. Apple
+// style versions with one to three components keep their number of
+// components; other semantic versions lose their pre-release and build parts.
+func nextVersion(versionString string, index int) (string, error) {
+	if version, ok := parseAppleVersion(versionString, maxMarketingVersionComponents); ok {
+		return version.bump(index).String(), nil
+	}
+
+	version, err := semver.Make(versionString)
+	if err != nil {
+		return versionString, fmt.Errorf("version %q can not be parsed: it must be one to three period-separated integers such as 1.2 or 1.2.3, or a semantic version such as 2.0.0-beta.1", versionString)
+	}
+	switch index {
+	case 0:
+		version.Major++
+		version.Minor = 0
+		version.Patch = 0
+	case 1:
+		version.Minor++
+		version.Patch = 0
+	default:
+		version.Patch++
+	}
+	version.Pre = nil
+	version.Build = nil
+	return version.String(), nil
+}
+
+// nextBuildNumber returns the build number after buildNumberString by
+// incrementing its last component, e.g. 42 to 43 or 1.2.3.4 to 1.2.3.5.
+// A file without a build number starts from 1.
+func nextBuildNumber(buildNumberString string) (string, error) {
+	if buildNumberString == "" {
+		return "1", nil
+	}
+	buildNumber, ok := parseAppleVersion(buildNumberString, 0)
+	if !ok {
+		return buildNumberString, fmt.Errorf("build number %q can not be parsed: it must be period-separated integers such as 42 or 1.2.3", buildNumberString)
+	}
+	return buildNumber.bump(len(buildNumber) - 1).String(), nil
+}
+
+// textEdit replaces src[start:end] with text.
+type textEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// applyTextEdits applies non-overlapping edits to src.
+func applyTextEdits(src []byte, edits []textEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var buf bytes.Buffer
+	offset := 0
+	for _, edit := range edits {
+		buf.Write(src[offset:edit.start])
+		buf.WriteString(edit.text)
+		offset = edit.end
+	}
+	buf.Write(src[offset:])
+	return buf.Bytes()
+}
+
+// VersionFileSet is a group of version files that share the version, such as
+// the Info.plist files of an app and its extensions. It is a VersionFile
+// itself: the version is read from the first file and written to all of them.
+type VersionFileSet struct {
+	configs []VersionFileConfig
+	files   []VersionFile
+}
+
+func LoadVersionFiles(configs []VersionFileConfig, fetch fetchFunc) (*VersionFileSet, error) {
+	set := VersionFileSet{configs: configs}
+	for _, config := range configs {
+		file, err := LoadVersionFile(config, fetch)
+		if err != nil {
+			return nil, err
+		}
+		set.files = append(set.files, file)
+	}
+	if len(set.files) == 0 {
+		return nil, fmt.Errorf("no version files")
+	}
+	return &set, nil
+}
+
+// Mismatches describes the files whose version or build number differs from
+// the first file. It is empty if all files start at the same version.
+func (set *VersionFileSet) Mismatches() []string {
+	var mismatches []string
+	for i, file := range set.files {
+		if file.Version() != set.Version() || file.BuildNumber() != set.BuildNumber() {
+			if len(mismatches) == 0 {
+				mismatches = append(mismatches, fmt.Sprintf("%s: %s (%s)", set.configs[0].Path, set.Version(), set.BuildNumber()))
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s (%s)", set.configs[i].Path, file.Version(), file.BuildNumber()))
+		}
+	}
+	return mismatches
+}
+
+func (set *VersionFileSet) Version() string {
+	return set.files[0].Version()
+}
+
+func (set *VersionFileSet) BuildNumber() string {
+	return set.files[0].BuildNumber()
+}
+
+func (set *VersionFileSet) NextMajor() (string, error) {
+	return set.files[0].NextMajor()
+}
+
+func (set *VersionFileSet) NextMinor() (string, error) {
+	return set.files[0].NextMinor()
+}
+
+func (set *VersionFileSet) NextPatch() (string, error) {
+	return set.files[0].NextPatch()
+}
+
+func (set *VersionFileSet) NextBuildNumber() (string, error) {
+	return set.files[0].NextBuildNumber()
+}
+
+func (set *VersionFileSet) SetVersion(version string, buildNumber string) {
+	for _, file := range set.files {
+		file.SetVersion(version, buildNumber)
+	}
+}
+
+// Files merges the updated files of all version files. Two version files may
+// update the same file, e.g. Info.plist files referring to one xcconfig, as
+// long as they render the same contents.
+func (set *VersionFileSet) Files() (map[string][]byte, error) {
+	merged := map[string][]byte{}
+	for _, file := range set.files {
+		files, err := file.Files()
+		if err != nil {
+			return nil, err
+		}
+		for path, content := range files {
+			if existing, ok := merged[path]; ok && !bytes.Equal(existing, content) {
+				return nil, fmt.Errorf("%s is updated by more than one version file with different contents", path)
+			}
+			merged[path] = content
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"howett.net/plist"
+)
+
+// testFetch returns a fetchFunc reading the files.
+func testFetch(files map[string]string) func(path string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("%s is not found", path)
+		}
+		return []byte(content), nil
+	}
+}
+
+const testInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2.0</string>
+	<key>CFBundleVersion</key>
+	<string>42</string>
+</dict>
+</plist>
+`
+
+func TestLoadVersionFile(t *testing.T) {
+	fetch := testFetch(map[string]string{
+		"App/Info.plist":     testInfoPlist,
+		"Version.txt":        "APP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+		"Version.xcconfig":   "APP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+		"Other/Info.plist":   "APP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+		"Broken.xcconfig":    "APP_VERSION = 1.2.0\n",
+		"Configurations.txt": testInfoPlist,
+	})
+
+	tests := []struct {
+		name    string
+		config  VersionFileConfig
+		wantErr bool
+	}{
+		{name: "infoplist", config: VersionFileConfig{Type: "infoplist", Path: "App/Info.plist"}},
+		{name: "xcconfig", config: VersionFileConfig{Type: "xcconfig", Path: "Version.xcconfig"}},
+		// The type is taken from the config, not from the file name.
+		{name: "xcconfig with another extension", config: VersionFileConfig{Type: "xcconfig", Path: "Version.txt"}},
+		{name: "xcconfig named Info.plist", config: VersionFileConfig{Type: "xcconfig", Path: "Other/Info.plist"}},
+		{name: "infoplist with another extension", config: VersionFileConfig{Type: "infoplist", Path: "Configurations.txt"}},
+		{name: "unknown type", config: VersionFileConfig{Type: "yaml", Path: "Version.xcconfig"}, wantErr: true},
+		{name: "missing file", config: VersionFileConfig{Type: "xcconfig", Path: "Missing.xcconfig"}, wantErr: true},
+		{name: "missing key", config: VersionFileConfig{Type: "xcconfig", Path: "Broken.xcconfig"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := LoadVersionFile(test.config, fetch)
+			if test.wantErr {
+				if err == nil {
+					t.Error("LoadVersionFile succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.Version() != "1.2.0" || file.BuildNumber() != "42" {
+				t.Errorf("got %s (%s), want 1.2.0 (42)", file.Version(), file.BuildNumber())
+			}
+
+			file.SetVersion("1.3.0", "43")
+			files, err := file.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 || files[test.config.Path] == nil {
+				t.Errorf("updated files = %v, want %s", files, test.config.Path)
+			}
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		major   string
+		minor   string
+		patch   string
+	}{
+		{version: "1.2.3", major: "2.0.0", minor: "1.3.0", patch: "1.2.4"},
+		{version: "0.9.9", major: "1.0.0", minor: "0.10.0", patch: "0.9.10"},
+		// Apple style versions keep their number of components.
+		{version: "1.2", major: "2.0", minor: "1.3", patch: "1.2.1"},
+		{version: "7", major: "8", minor: "7.1", patch: "7.0.1"},
+		// Other semantic versions lose their pre-release and build parts.
+		{version: "2.0.0-beta.1", major: "3.0.0", minor: "2.1.0", patch: "2.0.1"},
+		{version: "1.2.3+45", major: "2.0.0", minor: "1.3.0", patch: "1.2.4"},
+	}
+
+	for _, test := range tests {
+		major, err := nextMajor(test.version)
+		if err != nil || major != test.major {
+			t.Errorf("nextMajor(%q) = %q, %v, want %q", test.version, major, err, test.major)
+		}
+		minor, err := nextMinor(test.version)
+		if err != nil || minor != test.minor {
+			t.Errorf("nextMinor(%q) = %q, %v, want %q", test.version, minor, err, test.minor)
+		}
+		patch, err := nextPatch(test.version)
+		if err != nil || patch != test.patch {
+			t.Errorf("nextPatch(%q) = %q, %v, want %q", test.version, patch, err, test.patch)
+		}
+	}
+
+	for _, version := range []string{"one", "1.2.3.4", "1..2"} {
+		if _, err := nextMajor(version); err == nil {
+			t.Errorf("nextMajor(%q) succeeded", version)
+		}
+	}
+}
+
+func TestNextBuildNumber(t *testing.T) {
+	tests := []struct {
+		buildNumber string
+		want        string
+		wantErr     bool
+	}{
+		{buildNumber: "", want: "1"},
+		{buildNumber: "42", want: "43"},
+		{buildNumber: "1.9", want: "1.10"},
+		{buildNumber: "1.2.3", want: "1.2.4"},
+		{buildNumber: "1.2.3.4", want: "1.2.3.5"},
+		{buildNumber: "42a", wantErr: true},
+		{buildNumber: "1..2", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := nextBuildNumber(test.buildNumber)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("nextBuildNumber(%q) = %q, want error", test.buildNumber, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("nextBuildNumber(%q) = %q, %v, want %q", test.buildNumber, got, err, test.want)
+		}
+	}
+}
+
+func testPlistObject(t *testing.T, raw string) map[string]interface{} {
+	var object map[string]interface{}
+	if _, err := plist.Unmarshal([]byte(raw), &object); err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+func TestLoadVersionFileKeys(t *testing.T) {
+	infoPlist := func(version, buildNumber string) string {
+		return fmt.Sprintf("{\n\tCFBundleShortVersionString = %q;\n\tCFBundleVersion = %q;\n}\n", version, buildNumber)
+	}
+	const xcconfig = "MARKETING_VERSION = 1.2.0\nCURRENT_PROJECT_VERSION = 42\nAPP_BUILD = $(CURRENT_PROJECT_VERSION)\n"
+
+	tests := []struct {
+		name    string
+		config  VersionFileConfig
+		files   map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "xcconfig keys",
+			config: VersionFileConfig{Type: "xcconfig", Path: "Version.xcconfig", VersionKey: "MARKETING_VERSION", BuildNumberKey: "CURRENT_PROJECT_VERSION"},
+			files:  map[string]string{"Version.xcconfig": xcconfig},
+			want: map[string]string{
+				"Version.xcconfig": "MARKETING_VERSION = 1.3.0\nCURRENT_PROJECT_VERSION = 43\nAPP_BUILD = $(CURRENT_PROJECT_VERSION)\n",
+			},
+		},
+		{
+			name:   "plist keys",
+			config: VersionFileConfig{Type: "infoplist", Path: "Info.plist", VersionKey: "AppVersion", BuildNumberKey: "AppBuild"},
+			files:  map[string]string{"Info.plist": "{\n\tAppVersion = \"1.2.0\";\n\tAppBuild = \"42\";\n}\n"},
+			want:   map[string]string{"Info.plist": "{\n\tAppVersion = \"1.3.0\";\n\tAppBuild = \"43\";\n}\n"},
+		},
+		{
+			// The xcconfig is updated instead of the references in Info.plist.
+			name:   "references resolved from the xcconfig",
+			config: VersionFileConfig{Type: "infoplist", Path: "Info.plist", Xcconfig: "Version.xcconfig"},
+			files: map[string]string{
+				"Info.plist":       infoPlist("$(MARKETING_VERSION)", "${APP_BUILD}"),
+				"Version.xcconfig": xcconfig,
+			},
+			want: map[string]string{
+				"Version.xcconfig": "MARKETING_VERSION = 1.3.0\nCURRENT_PROJECT_VERSION = 43\nAPP_BUILD = $(CURRENT_PROJECT_VERSION)\n",
+			},
+		},
+		{
+			name:   "literal and reference",
+			config: VersionFileConfig{Type: "infoplist", Path: "Info.plist", Xcconfig: "Version.xcconfig"},
+			files: map[string]string{
+				"Info.plist":       infoPlist("$(MARKETING_VERSION)", "42"),
+				"Version.xcconfig": xcconfig,
+			},
+			want: map[string]string{
+				"Info.plist":       infoPlist("$(MARKETING_VERSION)", "43"),
+				"Version.xcconfig": "MARKETING_VERSION = 1.3.0\nCURRENT_PROJECT_VERSION = 42\nAPP_BUILD = $(CURRENT_PROJECT_VERSION)\n",
+			},
+		},
+		{
+			name:    "reference without xcconfig",
+			config:  VersionFileConfig{Type: "infoplist", Path: "Info.plist"},
+			files:   map[string]string{"Info.plist": infoPlist("$(MARKETING_VERSION)", "42")},
+			wantErr: true,
+		},
+		{
+			name:   "undefined reference",
+			config: VersionFileConfig{Type: "infoplist", Path: "Info.plist", Xcconfig: "Version.xcconfig"},
+			files: map[string]string{
+				"Info.plist":       infoPlist("$(APP_VERSION)", "42"),
+				"Version.xcconfig": xcconfig,
+			},
+			wantErr: true,
+		},
+		{
+			name:   "circular reference",
+			config: VersionFileConfig{Type: "infoplist", Path: "Info.plist", Xcconfig: "Version.xcconfig"},
+			files: map[string]string{
+				"Info.plist":       infoPlist("$(A)", "42"),
+				"Version.xcconfig": "A = $(B)\nB = $(A)\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := LoadVersionFile(test.config, testFetch(test.files))
+			if test.wantErr {
+				if err == nil {
+					t.Error("LoadVersionFile succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.Version() != "1.2.0" || file.BuildNumber() != "42" {
+				t.Fatalf("got %s (%s), want 1.2.0 (42)", file.Version(), file.BuildNumber())
+			}
+
+			file.SetVersion("1.3.0", "43")
+			files, err := file.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(test.want) {
+				t.Errorf("updated %d files, want %d", len(files), len(test.want))
+			}
+			for path, want := range test.want {
+				got := string(files[path])
+				if strings.HasSuffix(path, ".plist") {
+					// Only the values are compared; keeping the format is
+					// tested with the Info.plist editing.
+					if !reflect.DeepEqual(testPlistObject(t, got), testPlistObject(t, want)) {
+						t.Errorf("%s:\n%s\nwant\n%s", path, got, want)
+					}
+				} else if got != want {
+					t.Errorf("%s:\n%s\nwant\n%s", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVersionFiles(t *testing.T) {
+	xcconfig := VersionFileConfig{Type: "xcconfig", Path: "Version.xcconfig"}
+	widget := VersionFileConfig{Type: "xcconfig", Path: "Widget.xcconfig"}
+	watch := VersionFileConfig{Type: "xcconfig", Path: "Watch.xcconfig"}
+	fetch := testFetch(map[string]string{
+		"Version.xcconfig": "APP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+		"Widget.xcconfig":  "APP_VERSION = 1.2.0 // widget\nBUILD_VERSION = 42\n",
+		"Watch.xcconfig":   "APP_VERSION = 1.1.0\nBUILD_VERSION = 42\n",
+	})
+
+	set, err := LoadVersionFiles([]VersionFileConfig{xcconfig, widget}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches := set.Mismatches(); len(mismatches) != 0 {
+		t.Errorf("Mismatches() = %q", mismatches)
+	}
+	set.SetVersion("1.3.0", "43")
+	files, err := set.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Version.xcconfig": "APP_VERSION = 1.3.0\nBUILD_VERSION = 43\n",
+		"Widget.xcconfig":  "APP_VERSION = 1.3.0 // widget\nBUILD_VERSION = 43\n",
+	}
+	if len(files) != len(want) {
+		t.Errorf("updated %d files, want %d", len(files), len(want))
+	}
+	for path, content := range want {
+		if got := string(files[path]); got != content {
+			t.Errorf("%s = %q, want %q", path, got, content)
+		}
+	}
+
+	set, err = LoadVersionFiles([]VersionFileConfig{xcconfig, widget, watch}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMismatches := []string{"Version.xcconfig: 1.2.0 (42)", "Watch.xcconfig: 1.1.0 (42)"}
+	if mismatches := set.Mismatches(); !reflect.DeepEqual(mismatches, wantMismatches) {
+		t.Errorf("Mismatches() = %q, want %q", mismatches, wantMismatches)
+	}
+
+	if _, err := LoadVersionFiles([]VersionFileConfig{xcconfig, {Type: "xcconfig", Path: "Missing.xcconfig"}}, fetch); err == nil {
+		t.Error("LoadVersionFiles succeeded with a missing file")
+	}
+}
+
+func TestVersionFileSetSharedFile(t *testing.T) {
+	// Info.plist files of an app and its extension refer to one xcconfig.
+	infoPlist := "{\n\tCFBundleShortVersionString = \"$(MARKETING_VERSION)\";\n\tCFBundleVersion = \"$(CURRENT_PROJECT_VERSION)\";\n}\n"
+	fetch := testFetch(map[string]string{
+		"App/Info.plist":    infoPlist,
+		"Widget/Info.plist": infoPlist,
+		"Version.xcconfig":  "MARKETING_VERSION = 1.2.0\nCURRENT_PROJECT_VERSION = 42\n",
+	})
+	configs := []VersionFileConfig{
+		{Type: "infoplist", Path: "App/Info.plist", Xcconfig: "Version.xcconfig"},
+		{Type: "infoplist", Path: "Widget/Info.plist", Xcconfig: "Version.xcconfig"},
+	}
+
+	set, err := LoadVersionFiles(configs, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SetVersion("1.3.0", "43")
+	files, err := set.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || string(files["Version.xcconfig"]) != "MARKETING_VERSION = 1.3.0\nCURRENT_PROJECT_VERSION = 43\n" {
+		t.Errorf("Files() = %q", files)
+	}
+
+	// A file rendered differently by two version files can not be committed.
+	set.files[1].SetVersion("1.4.0", "44")
+	if _, err := set.Files(); err == nil {
+		t.Error("Files() succeeded with conflicting contents")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVersioningScheme = "semver"
+	defaultCalVerFormat     = "YY.MM.MICRO"
+)
+
+// versionRequest is what a versioning scheme computes the next versions from.
+type versionRequest struct {
+	file     VersionFile
+	channels []string
+	// format is the version format of the app, such as YY.0M.MICRO for
+	// calendar versions.
+	format string
+	now    time.Time
+}
+
+type versioningScheme struct {
+	next func(request versionRequest) ([]string, error)
+	// bump returns the next version with a major, minor or patch bump. It is
+	// nil if the scheme has no such components.
+	bump func(file VersionFile, bump string) (string, error)
+	// validate checks a version entered by hand.
+	validate func(version string, format string) error
+}
+
+var versioningSchemes = map[string]versioningScheme{
+	"semver": {
+		next: func(request versionRequest) ([]string, error) {
+			return nextVersions(request.file, request.channels)
+		},
+		bump: func(file VersionFile, bump string) (string, error) {
+			switch bump {
+			case bumpMajor:
+				return file.NextMajor()
+			case bumpMinor:
+				return file.NextMinor()
+			default:
+				return file.NextPatch()
+			}
+		},
+		validate: func(version string, _ string) error {
+			_, err := nextPatch(version)
+			return err
+		},
+	},
+	"calver": {
+		next: nextCalVersions,
+		validate: func(version string, format string) error {
+			calVerFormat, err := parseCalVerFormat(format)
+			if err != nil {
+				return err
+			}
+			_, err = parseCalVersion(version, calVerFormat)
+			return err
+		},
+	},
+}
+
+func versioningSchemeNames() []string {
+	var names []string
+	for name := range versioningSchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// calVerFormat is the format of calendar versions: YY.MM.MICRO, where the
+// year may be written in full (YYYY) and the month zero-padded (0M).
+type calVerFormat struct {
+	fullYear bool
+	padMonth bool
+}
+
+func parseCalVerFormat(s string) (calVerFormat, error) {
+	components := strings.Split(s, ".")
+	if len(components) != 3 || components[2] != "MICRO" {
+		return calVerFormat{}, fmt.Errorf("calendar version format %q must be YEAR.MONTH.MICRO, such as YY.0M.MICRO", s)
+	}
+	var format calVerFormat
+	switch components[0] {
+	case "YY":
+	case "YYYY":
+		format.fullYear = true
+	default:
+		return calVerFormat{}, fmt.Errorf("calendar version format %q must start with YY or YYYY", s)
+	}
+	switch components[1] {
+	case "MM":
+	case "0M":
+		format.padMonth = true
+	default:
+		return calVerFormat{}, fmt.Errorf("calendar version format %q must have MM or 0M months", s)
+	}
+	return format, nil
+}
+
+func (format calVerFormat) String() string {
+	year, month := "YY", "MM"
+	if format.fullYear {
+		year = "YYYY"
+	}
+	if format.padMonth {
+		month = "0M"
+	}
+	return fmt.Sprintf("%s.%s.MICRO", year, month)
+}
+
+// calVersion is a calendar version in a calVerFormat.
+type calVersion struct {
+	year   int
+	month  int
+	micro  int
+	format calVerFormat
+}
+
+func parseCalVersion(s string, format calVerFormat) (calVersion, error) {
+	version, ok := parseAppleVersion(s, 3)
+	if !ok || len(version) != 3 || version[1] < 1 || version[1] > 12 {
+		return calVersion{}, fmt.Errorf("version %q is not a %s calendar version", s, format)
+	}
+	components := strings.Split(s, ".")
+	year, month := components[0], components[1]
+	if format.fullYear != (len(year) == 4) || format.padMonth && len(month) != 2 || !format.padMonth && month[0] == '0' {
+		return calVersion{}, fmt.Errorf("version %q is not a %s calendar version", s, format)
+	}
+	return calVersion{
+		year:   int(version[0]),
+		month:  int(version[1]),
+		micro:  int(version[2]),
+		format: format,
+	}, nil
+}
+
+// at returns the first release of the month of t in the format of version.
+func (version calVersion) at(t time.Time) calVersion {
+	next := version
+	next.year = t.Year()
+	if !version.format.fullYear {
+		next.year -= 2000
+	}
+	next.month = int(t.Month())
+	next.micro = 0
+	return next
+}
+
+// after reports whether version is from a later month than other.
+func (version calVersion) after(other calVersion) bool {
+	if version.year != other.year {
+		return version.year > other.year
+	}
+	return version.month > other.month
+}
+
+func (version calVersion) String() string {
+	month := strconv.Itoa(version.month)
+	if version.format.padMonth && version.month < 10 {
+		month = "0" + month
+	}
+	return fmt.Sprintf("%d.%s.%d", version.year, month, version.micro)
+}
+
+// nextCalVersions returns the next release of this month, which is the first
+// one if the current version is from an earlier month, and the next micro
+// release of the current version.
+func nextCalVersions(request versionRequest) ([]string, error) {
+	format, err := parseCalVerFormat(request.format)
+	if err != nil {
+		return nil, err
+	}
+	current, err := parseCalVersion(request.file.Version(), format)
+	if err != nil {
+		return nil, err
+	}
+	micro := current
+	micro.micro++
+
+	// In the month of the current version, its next micro release is also
+	// the next release of this month.
+	var versions []string
+	if thisMonth := current.at(request.now); thisMonth.after(current) {
+		versions = append(versions, thisMonth.String())
+	}
+	return append(versions, micro.String()), nil
+}
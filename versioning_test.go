@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNextCalVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		version string
+		now     time.Time
+		want    []string
+	}{
+		{
+			name:    "same month",
+			format:  "YY.MM.MICRO",
+			version: "24.5.2",
+			now:     time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC),
+			want:    []string{"24.5.3"},
+		},
+		{
+			name:    "next month",
+			format:  "YY.MM.MICRO",
+			version: "24.5.2",
+			now:     time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"24.6.0", "24.5.3"},
+		},
+		{
+			name:    "year rollover",
+			format:  "YY.MM.MICRO",
+			version: "24.12.3",
+			now:     time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			want:    []string{"25.1.0", "24.12.4"},
+		},
+		{
+			name:    "full year",
+			format:  "YYYY.MM.MICRO",
+			version: "2024.12.3",
+			now:     time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2025.1.0", "2024.12.4"},
+		},
+		{
+			name:    "padded month",
+			format:  "YY.0M.MICRO",
+			version: "24.09.1",
+			now:     time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"24.10.0", "24.09.2"},
+		},
+		{
+			// The format is taken from the config, not from the current
+			// version, whose month needs no padding.
+			name:    "padded month after a two-digit month",
+			format:  "YY.0M.MICRO",
+			version: "24.11.0",
+			now:     time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			want:    []string{"25.01.0", "24.11.1"},
+		},
+		{
+			name:    "version from a later month than the clock",
+			format:  "YY.MM.MICRO",
+			version: "24.6.0",
+			now:     time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+			want:    []string{"24.6.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := nextCalVersions(versionRequest{file: testVersionFile(t, test.version), format: test.format, now: test.now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCalVersion(t *testing.T) {
+	tests := []struct {
+		format  string
+		valid   []string
+		invalid []string
+	}{
+		{
+			format:  "YY.MM.MICRO",
+			valid:   []string{"24.5.2", "24.12.10"},
+			invalid: []string{"24.5", "24.13.0", "24.0.1", "24.05.2", "2024.5.2", "24.5.2.1", "v24.5.2", "1.2.3-beta.1"},
+		},
+		{
+			format:  "YY.0M.MICRO",
+			valid:   []string{"24.05.2", "24.11.0"},
+			invalid: []string{"24.5.2", "24.005.2", "2024.05.2"},
+		},
+		{
+			format:  "YYYY.0M.MICRO",
+			valid:   []string{"2024.05.0"},
+			invalid: []string{"24.05.0", "2024.5.0"},
+		},
+	}
+
+	for _, test := range tests {
+		format, err := parseCalVerFormat(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := format.String(); got != test.format {
+			t.Errorf("parseCalVerFormat(%q).String() = %q", test.format, got)
+		}
+		for _, version := range test.valid {
+			if _, err := parseCalVersion(version, format); err != nil {
+				t.Errorf("parseCalVersion(%q, %s): %s", version, test.format, err)
+			}
+		}
+		for _, version := range test.invalid {
+			if _, err := parseCalVersion(version, format); err == nil {
+				t.Errorf("parseCalVersion(%q, %s) succeeded", version, test.format)
+			}
+		}
+	}
+}
+
+func TestParseCalVerFormat(t *testing.T) {
+	for _, format := range []string{"", "YY.MM", "YY.MM.PATCH", "YYY.MM.MICRO", "YY.M.MICRO", "MM.YY.MICRO"} {
+		if _, err := parseCalVerFormat(format); err == nil {
+			t.Errorf("parseCalVerFormat(%q) succeeded", format)
+		}
+	}
+}
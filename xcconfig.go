@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+const (
+	xcconfigVersionKey     = "APP_VERSION"
+	xcconfigBuildNumberKey = "BUILD_VERSION"
+)
+
+// xcconfigAssignmentPattern matches `KEY[cond=value][cond=value] = value` with
+// an optional trailing semicolon. Only the first `=` after the key separates
+// the key from the value, so values may contain `=`.
+var xcconfigAssignmentPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)((?:\[[^\]]*\])*)\s*=[ \t]*(.*?)[ \t]*;?[ \t]*$`)
+
+// xcconfigSetting is a build setting assignment. start and end are the byte
+// offsets of the value in the file.
+type xcconfigSetting struct {
+	key        string
+	conditions string
+	value      string
+	start      int
+	end        int
+}
+
+// xcconfigFile edits build settings in an .xcconfig file in place. Everything
+// other than the values of the edited settings is kept byte-for-byte.
+type xcconfigFile struct {
+	raw      []byte
+	settings []xcconfigSetting
+	updates  map[string]string
+}
+
+func parseXcconfig(raw []byte) *xcconfigFile {
+	xcconfig := xcconfigFile{
+		raw:     raw,
+		updates: map[string]string{},
+	}
+
+	offset := 0
+	for _, line := range bytes.SplitAfter(raw, []byte("\n")) {
+		code := bytes.TrimRight(line, "\r\n")
+		if i := bytes.Index(code, []byte("//")); i >= 0 {
+			code = code[:i]
+		}
+
+		if m := xcconfigAssignmentPattern.FindSubmatchIndex(code); m != nil {
+			xcconfig.settings = append(xcconfig.settings, xcconfigSetting{
+				key:        string(code[m[2]:m[3]]),
+				conditions: string(code[m[4]:m[5]]),
+				value:      string(code[m[6]:m[7]]),
+				start:      offset + m[6],
+				end:        offset + m[7],
+			})
+		}
+
+		offset += len(line)
+	}
+
+	return &xcconfig
+}
+
+// assignments returns the assignments of key that hold its value: the
+// unconditional ones, or the conditional ones when the key is only assigned
+// under conditions such as `KEY[sdk=iphoneos*]`.
+func (xcconfig *xcconfigFile) assignments(key string) []xcconfigSetting {
+	var unconditional, conditional []xcconfigSetting
+	for _, setting := range xcconfig.settings {
+		if setting.key != key {
+			continue
+		}
+		if setting.conditions == "" {
+			unconditional = append(unconditional, setting)
+		} else {
+			conditional = append(conditional, setting)
+		}
+	}
+	if len(unconditional) > 0 {
+		return unconditional
+	}
+	return conditional
+}
+
+// Value returns the value of the setting. When the key is assigned more than
+// once, the last assignment wins as it does in Xcode.
+func (xcconfig *xcconfigFile) Value(key string) (string, bool) {
+	if value, ok := xcconfig.updates[key]; ok {
+		return value, true
+	}
+
+	assignments := xcconfig.assignments(key)
+	if len(assignments) == 0 {
+		return "", false
+	}
+	return assignments[len(assignments)-1].value, true
+}
+
+// SetValue replaces the value of every assignment Value reads from.
+func (xcconfig *xcconfigFile) SetValue(key, value string) {
+	xcconfig.updates[key] = value
+}
+
+// Changed reports whether any setting has been updated.
+func (xcconfig *xcconfigFile) Changed() bool {
+	return len(xcconfig.updates) > 0
+}
+
+func (xcconfig *xcconfigFile) Bytes() ([]byte, error) {
+	var edits []textEdit
+	for key, value := range xcconfig.updates {
+		for _, setting := range xcconfig.assignments(key) {
+			edits = append(edits, textEdit{start: setting.start, end: setting.end, text: value})
+		}
+	}
+	return applyTextEdits(xcconfig.raw, edits), nil
+}
+
+// Xcconfig is a version file that keeps the version and build number in
+// build settings of an .xcconfig file.
+type Xcconfig struct {
+	path           string
+	versionKey     string
+	buildNumberKey string
+	file           *xcconfigFile
+}
+
+func NewXcconfig(config VersionFileConfig, raw []byte) (*Xcconfig, error) {
+	file := parseXcconfig(raw)
+	for _, key := range []string{config.VersionKey, config.BuildNumberKey} {
+		if _, ok := file.Value(key); !ok {
+			return nil, fmt.Errorf("%s is not found", key)
+		}
+	}
+
+	xcconfig := Xcconfig{
+		path:           config.Path,
+		versionKey:     config.VersionKey,
+		buildNumberKey: config.BuildNumberKey,
+		file:           file,
+	}
+	return &xcconfig, nil
+}
+
+func (xcconfig *Xcconfig) Version() string {
+	value, _ := xcconfig.file.Value(xcconfig.versionKey)
+	return value
+}
+
+func (xcconfig *Xcconfig) BuildNumber() string {
+	value, _ := xcconfig.file.Value(xcconfig.buildNumberKey)
+	return value
+}
+
+func (xcconfig *Xcconfig) NextMajor() (string, error) {
+	return nextMajor(xcconfig.Version())
+}
+
+func (xcconfig *Xcconfig) NextMinor() (string, error) {
+	return nextMinor(xcconfig.Version())
+}
+
+func (xcconfig *Xcconfig) NextPatch() (string, error) {
+	return nextPatch(xcconfig.Version())
+}
+
+func (xcconfig *Xcconfig) NextBuildNumber() (string, error) {
+	return nextBuildNumber(xcconfig.BuildNumber())
+}
+
+func (xcconfig *Xcconfig) SetVersion(version string, build string) {
+	xcconfig.file.SetValue(xcconfig.versionKey, version)
+	xcconfig.file.SetValue(xcconfig.buildNumberKey, build)
+}
+
+func (xcconfig *Xcconfig) Files() (map[string][]byte, error) {
+	bytes, err := xcconfig.file.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{xcconfig.path: bytes}, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import "testing"
+
+func TestXcconfig(t *testing.T) {
+	config := VersionFileConfig{Path: "Version.xcconfig", VersionKey: xcconfigVersionKey, BuildNumberKey: xcconfigBuildNumberKey}
+
+	tests := []struct {
+		name        string
+		raw         string
+		version     string
+		buildNumber string
+		want        string
+	}{
+		{
+			name:        "plain",
+			raw:         "APP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION = 1.3.0\nBUILD_VERSION = 43\n",
+		},
+		{
+			name:        "include and comments",
+			raw:         "#include \"Base.xcconfig\"\n#include? \"Local.xcconfig\"\n\n// Version\nAPP_VERSION = 1.2.0 // marketing version\nBUILD_VERSION=42;\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "#include \"Base.xcconfig\"\n#include? \"Local.xcconfig\"\n\n// Version\nAPP_VERSION = 1.3.0 // marketing version\nBUILD_VERSION=43;\n",
+		},
+		{
+			name:        "conditional keys are kept when unconditional ones exist",
+			raw:         "APP_VERSION = 1.2.0\nAPP_VERSION[sdk=iphonesimulator*] = 0.0.1\nBUILD_VERSION = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION = 1.3.0\nAPP_VERSION[sdk=iphonesimulator*] = 0.0.1\nBUILD_VERSION = 43\n",
+		},
+		{
+			name:        "only conditional keys",
+			raw:         "APP_VERSION[sdk=iphoneos*] = 1.2.0\nAPP_VERSION[sdk=iphonesimulator*][arch=*] = 1.2.0\nBUILD_VERSION = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION[sdk=iphoneos*] = 1.3.0\nAPP_VERSION[sdk=iphonesimulator*][arch=*] = 1.3.0\nBUILD_VERSION = 43\n",
+		},
+		{
+			name:        "last assignment wins",
+			raw:         "APP_VERSION = 1.0.0\nAPP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION = 1.3.0\nAPP_VERSION = 1.3.0\nBUILD_VERSION = 43\n",
+		},
+		{
+			name:        "equals sign inside other values",
+			raw:         "OTHER_SWIFT_FLAGS = -D FLAVOR=prod\nAPP_VERSION = 1.2.0\nBUILD_VERSION = 42\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "OTHER_SWIFT_FLAGS = -D FLAVOR=prod\nAPP_VERSION = 1.3.0\nBUILD_VERSION = 43\n",
+		},
+		{
+			name:        "CRLF line endings",
+			raw:         "APP_VERSION = 1.2.0\r\nBUILD_VERSION = 42\r\n",
+			version:     "1.2.0",
+			buildNumber: "42",
+			want:        "APP_VERSION = 1.3.0\r\nBUILD_VERSION = 43\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			xcconfig, err := NewXcconfig(config, []byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if xcconfig.Version() != test.version || xcconfig.BuildNumber() != test.buildNumber {
+				t.Fatalf("got %s (%s), want %s (%s)", xcconfig.Version(), xcconfig.BuildNumber(), test.version, test.buildNumber)
+			}
+
+			xcconfig.SetVersion("1.3.0", "43")
+			files, err := xcconfig.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(files[config.Path]); got != test.want {
+				t.Errorf("got\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestXcconfigMissingKey(t *testing.T) {
+	config := VersionFileConfig{Path: "Version.xcconfig", VersionKey: xcconfigVersionKey, BuildNumberKey: xcconfigBuildNumberKey}
+
+	for _, raw := range []string{
+		"APP_VERSION = 1.2.0\n",
+		"// BUILD_VERSION = 42\nAPP_VERSION = 1.2.0\n",
+		"#include \"Version.xcconfig\"\n",
+	} {
+		if _, err := NewXcconfig(config, []byte(raw)); err == nil {
+			t.Errorf("NewXcconfig(%q) succeeded", raw)
+		}
+	}
+}